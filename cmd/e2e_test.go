@@ -2,10 +2,12 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"testing"
 
+	"github.com/nirmata/json-image-verification/pkg/report"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -113,7 +115,8 @@ func Test_Verify(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			out := bytes.NewBufferString("")
-			verify(out, tt.resourcePath, tt.policyPath)
+			_, err := verify(context.Background(), out, options{resourcePath: tt.resourcePath, policyPath: tt.policyPath, maxWorkers: 1, output: report.Text})
+			assert.NoError(t, err)
 			actual, err := io.ReadAll(out)
 			assert.NoError(t, err)
 			if tt.outputPath != "" {
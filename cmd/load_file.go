@@ -12,6 +12,7 @@ import (
 	yamlutils "github.com/kyverno/pkg/ext/yaml"
 	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
 	"github.com/nirmata/json-image-verification/pkg/data"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/kubectl-validate/pkg/openapiclient"
 )
@@ -19,21 +20,31 @@ import (
 var (
 	gv                               = schema.GroupVersion{Group: "nirmata.io", Version: "v1alpha1"}
 	imageVerificationPolicy_v1alpha1 = gv.WithKind("ImageVerificationPolicy")
+	policyException_v1alpha1         = gv.WithKind("PolicyException")
 )
 
 func Load(path ...string) ([]*v1alpha1.ImageVerificationPolicy, error) {
-	var policies []*v1alpha1.ImageVerificationPolicy
-	for _, path := range path {
-		p, err := load(path)
+	return loadAll(path, Parse)
+}
+
+// LoadExceptions loads the policy exceptions of the files and directories
+func LoadExceptions(path ...string) ([]*v1alpha1.PolicyException, error) {
+	return loadAll(path, ParseExceptions)
+}
+
+func loadAll[T any](paths []string, parse func([]byte) ([]*T, error)) ([]*T, error) {
+	var objects []*T
+	for _, path := range paths {
+		o, err := load(path, parse)
 		if err != nil {
 			return nil, err
 		}
-		policies = append(policies, p...)
+		objects = append(objects, o...)
 	}
-	return policies, nil
+	return objects, nil
 }
 
-func load(path string) ([]*v1alpha1.ImageVerificationPolicy, error) {
+func load[T any](path string, parse func([]byte) ([]*T, error)) ([]*T, error) {
 	var files []string
 	err := filepath.Walk(path, func(file string, info fs.FileInfo, err error) error {
 		if err != nil {
@@ -47,50 +58,92 @@ func load(path string) ([]*v1alpha1.ImageVerificationPolicy, error) {
 	if err != nil {
 		return nil, err
 	}
-	var policies []*v1alpha1.ImageVerificationPolicy
+	var objects []*T
 	for _, path := range files {
 		content, err := os.ReadFile(filepath.Clean(path))
 		if err != nil {
 			return nil, err
 		}
-		p, err := Parse(content)
+		o, err := parse(content)
 		if err != nil {
 			return nil, err
 		}
-		policies = append(policies, p...)
+		objects = append(objects, o...)
 	}
-	return policies, nil
+	return objects, nil
 }
 
 func Parse(content []byte) ([]*v1alpha1.ImageVerificationPolicy, error) {
-	documents, err := yamlutils.SplitDocuments(content)
+	var policies []*v1alpha1.ImageVerificationPolicy
+	err := parseDocuments(content, func(gvk schema.GroupVersionKind, untyped unstructured.Unstructured) error {
+		switch gvk {
+		case imageVerificationPolicy_v1alpha1:
+			policy, err := convert.To[v1alpha1.ImageVerificationPolicy](untyped)
+			if err != nil {
+				return err
+			}
+			if errs := policy.Validate(); len(errs) > 0 {
+				return fmt.Errorf("invalid policy %s: %w", policy.Name, errs.ToAggregate())
+			}
+			policies = append(policies, policy)
+		default:
+			return fmt.Errorf("policy type not supported %s", gvk)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	crds, err := data.Crds()
+	return policies, nil
+}
+
+// ParseExceptions parses the policy exceptions of the YAML documents
+func ParseExceptions(content []byte) ([]*v1alpha1.PolicyException, error) {
+	var exceptions []*v1alpha1.PolicyException
+	err := parseDocuments(content, func(gvk schema.GroupVersionKind, untyped unstructured.Unstructured) error {
+		switch gvk {
+		case policyException_v1alpha1:
+			exception, err := convert.To[v1alpha1.PolicyException](untyped)
+			if err != nil {
+				return err
+			}
+			if errs := exception.Validate(); len(errs) > 0 {
+				return fmt.Errorf("invalid policy exception %s: %w", exception.Name, errs.ToAggregate())
+			}
+			exceptions = append(exceptions, exception)
+		default:
+			return fmt.Errorf("policy exception type not supported %s", gvk)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return exceptions, nil
+}
+
+// parseDocuments loads every YAML document of the content against the CRDs and passes it to add
+func parseDocuments(content []byte, add func(schema.GroupVersionKind, unstructured.Unstructured) error) error {
+	documents, err := yamlutils.SplitDocuments(content)
+	if err != nil {
+		return err
+	}
+	crds, err := data.Crds()
+	if err != nil {
+		return err
+	}
 	loader, err := loader.New(openapiclient.NewLocalCRDFiles(crds))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var policies []*v1alpha1.ImageVerificationPolicy
 	for _, document := range documents {
 		gvk, untyped, err := loader.Load(document)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		switch gvk {
-		case imageVerificationPolicy_v1alpha1:
-			policy, err := convert.To[v1alpha1.ImageVerificationPolicy](untyped)
-			if err != nil {
-				return nil, err
-			}
-			policies = append(policies, policy)
-		default:
-			return nil, fmt.Errorf("policy type not supported %s", gvk)
+		if err := add(gvk, untyped); err != nil {
+			return err
 		}
 	}
-	return policies, nil
+	return nil
 }
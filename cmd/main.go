@@ -1,67 +1,368 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/cache"
 	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+	"github.com/nirmata/json-image-verification/pkg/report"
+	"sigs.k8s.io/yaml"
 )
 
+// Process exit codes
+const (
+	exitPass  = 0
+	exitFail  = 1
+	exitError = 2
+	exitUsage = 3
+)
+
+// failOn is the least severe outcome failing the verification, outcomes are ordered by severity
+// from skip to fail to error and an error always exits with exitError
+type failOn string
+
+const (
+	failOnSkip  failOn = "skip"
+	failOnFail  failOn = "fail"
+	failOnError failOn = "error"
+)
+
+var failOnValues = []failOn{failOnSkip, failOnFail, failOnError}
+
+type options struct {
+	resourcePath string
+	policyPath   string
+	maxWorkers   int
+	cacheSize    int
+	cacheTTL     time.Duration
+	cacheDir     string
+	output       report.Format
+	failOn       failOn
+
+	// exceptionPath is the path of the policy exceptions, no failure is waived when empty
+	exceptionPath string
+
+	// registry authentication, registries are accessed anonymously when none is set
+	registryCredentialsPath string
+	dockerConfig            string
+	credentialHelpers       string
+
+	// registry connections, registries are accessed with HTTPS trusting the system roots when none is set
+	registryConfigPath string
+	insecureRegistries string
+	registryProxy      string
+	registryNoProxy    string
+	registryMirrors    string
+
+	// ociLayoutPath is the path of the OCI layout the images are verified from instead of the registries
+	ociLayoutPath string
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("usage: ./verifier <POLICY> <RESOURCE>")
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	os.Exit(code)
+}
+
+// run verifies the resource with the policies given by the command line arguments and returns the process exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(stderr, "error: unexpected failure: %v\n", r)
+			code = exitError
+		}
+	}()
+
+	if len(args) > 0 && args[0] == "serve" {
+		return serve(ctx, args[1:], stderr)
+	}
+	if len(args) > 0 && args[0] == "webhook" {
+		return webhookCommand(ctx, args[1:], stderr)
 	}
-	policyPath := flag.String("policy", "", "path to policy")
-	resourcePath := flag.String("resource", "", "path to resource")
-	flag.Parse()
 
-	verify(os.Stdout, *resourcePath, *policyPath)
+	opts := options{output: report.Text, failOn: failOnFail}
+	flags := flag.NewFlagSet("verifier", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.resourcePath, "resource", "", "path to resource")
+	addEngineFlags(flags, &opts)
+	flags.Var(&formatValue{&opts.output}, "output", fmt.Sprintf("output format, one of %v", report.Formats))
+	flags.Var(&failOnValue{&opts.failOn}, "fail-on", fmt.Sprintf("least severe rule outcome failing the verification, one of %v", failOnValues))
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitPass
+		}
+		return exitUsage
+	}
+	if opts.policyPath == "" || opts.resourcePath == "" || flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: verifier --policy <POLICY> --resource <RESOURCE> [flags]\n       verifier serve --policy <POLICY> [flags]\n       verifier webhook --cert-dir <DIR> [flags]")
+		flags.PrintDefaults()
+		return exitUsage
+	}
+
+	response, err := verify(ctx, stdout, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return exitCode(response, opts.failOn)
 }
 
-func verify(out io.Writer, resourcePath, policyPath string) {
-	b, err := os.ReadFile(resourcePath)
+// exitCode returns the exit code of the verification from the rule outcomes, the failures of
+// rules in Audit mode are ignored
+func exitCode(response imageverifier.Response, failOn failOn) int {
+	failed, skipped := false, false
+	for _, p := range response.PolicyResponses {
+		for _, r := range p.RuleResponses {
+			switch {
+			case r.VerificationOutcome == imageverifier.ERROR && r.Blocking():
+				return exitError
+			case r.VerificationOutcome == imageverifier.FAIL && r.Blocking():
+				failed = true
+			case r.VerificationOutcome == imageverifier.SKIP:
+				skipped = true
+			}
+		}
+	}
+	if failed && failOn != failOnError {
+		return exitFail
+	}
+	if skipped && failOn == failOnSkip {
+		return exitFail
+	}
+	return exitPass
+}
+
+// failOnValue is a flag.Value accepting the supported fail-on outcomes
+type failOnValue struct {
+	failOn *failOn
+}
+
+func (f *failOnValue) String() string {
+	if f.failOn == nil {
+		return ""
+	}
+	return string(*f.failOn)
+}
+
+func (f *failOnValue) Set(s string) error {
+	for _, v := range failOnValues {
+		if string(v) == s {
+			*f.failOn = v
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %v", failOnValues)
+}
+
+// formatValue is a flag.Value accepting the supported output formats
+type formatValue struct {
+	format *report.Format
+}
+
+func (f *formatValue) String() string {
+	if f.format == nil {
+		return ""
+	}
+	return string(*f.format)
+}
+
+func (f *formatValue) Set(s string) error {
+	for _, format := range report.Formats {
+		if string(format) == s {
+			*f.format = format
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %v", report.Formats)
+}
+
+// addEngineFlags registers the flags shared by the commands configuring policies and the engine
+func addEngineFlags(flags *flag.FlagSet, opts *options) {
+	flags.StringVar(&opts.policyPath, "policy", "", "path to policy")
+	flags.StringVar(&opts.exceptionPath, "exception", "", "path to policy exceptions waiving verification failures")
+	flags.IntVar(&opts.maxWorkers, "max-workers", 1, "maximum number of verifications running concurrently")
+	flags.IntVar(&opts.cacheSize, "cache-size", 0, "maximum number of verification results kept in memory, 0 disables the cache")
+	flags.DurationVar(&opts.cacheTTL, "cache-ttl", time.Hour, "duration after which cached verification results expire")
+	flags.StringVar(&opts.cacheDir, "cache-dir", "", "directory where verification results are persisted across runs")
+	flags.StringVar(&opts.registryCredentialsPath, "registry-credentials", "", "path to a file listing the username and password or the token of registries")
+	flags.StringVar(&opts.dockerConfig, "docker-config", "", "path to a docker config.json file whose credentials are used to access registries")
+	flags.StringVar(&opts.credentialHelpers, "credential-helpers", "", fmt.Sprintf("comma separated credential helpers used to access registries, any of %v", registry.CredentialHelpers))
+	flags.StringVar(&opts.registryConfigPath, "registry-config", "", "path to a file listing the CA bundle, the client certificate and the insecure setting of registries")
+	flags.StringVar(&opts.insecureRegistries, "insecure-registries", "", "comma separated registries accessed with plain HTTP")
+	flags.StringVar(&opts.registryProxy, "registry-proxy", "", "URL of the HTTP(S) proxy used to access registries, the proxy environment variables are used by default")
+	flags.StringVar(&opts.registryNoProxy, "registry-no-proxy", "", "comma separated hosts, domains and CIDRs of registries accessed without --registry-proxy")
+	flags.StringVar(&opts.ociLayoutPath, "oci-layout", "", "path to an OCI image layout directory or tarball the images are verified from instead of the registries")
+	flags.StringVar(&opts.registryMirrors, "registry-mirrors", "", "comma separated prefix=mirror rewrites of the images pulled from registries, e.g. docker.io/*=mirror.corp/dockerhub/*")
+}
+
+func engineOptions(opts options) ([]imageverifier.Option, error) {
+	var engineOpts []imageverifier.Option
+	if opts.maxWorkers > 1 {
+		engineOpts = append(engineOpts, imageverifier.WithConcurrency(imageverifier.Concurrency{
+			Images:            true,
+			VerificationRules: true,
+			MaxWorkers:        opts.maxWorkers,
+		}))
+	}
+	var caches []cache.Cache
+	if opts.cacheSize > 0 {
+		caches = append(caches, cache.NewLRU(opts.cacheSize, opts.cacheTTL))
+	}
+	if opts.cacheDir != "" {
+		c, err := cache.NewDisk(opts.cacheDir, opts.cacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		caches = append(caches, c)
+	}
+	if len(caches) > 0 {
+		engineOpts = append(engineOpts, imageverifier.WithCache(cache.NewTiered(caches...)))
+	}
+	auth, err := registryAuth(opts)
+	if err != nil {
+		return nil, err
+	}
+	keychain, err := auth.Keychain()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := registryTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	roundTripper, err := transport.RoundTripper()
+	if err != nil {
+		return nil, err
+	}
+	engineOpts = append(engineOpts, imageverifier.WithKeychain(keychain), imageverifier.WithTransport(roundTripper))
+	mirrors, err := registryMirrors(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(mirrors) > 0 {
+		engineOpts = append(engineOpts, imageverifier.WithMirrors(mirrors))
+	}
+	if opts.ociLayoutPath != "" {
+		layout, err := registry.OpenLayout(opts.ociLayoutPath)
+		if err != nil {
+			return nil, err
+		}
+		engineOpts = append(engineOpts, imageverifier.WithBackend(layout))
+	}
+	return engineOpts, nil
+}
+
+// splitList returns the non empty entries of a comma separated list
+func splitList(s string) []string {
+	var list []string
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// registryAuth returns the registry authentication configured by the options
+func registryAuth(opts options) (registry.Auth, error) {
+	auth := registry.Auth{DockerConfig: opts.dockerConfig, CredentialHelpers: splitList(opts.credentialHelpers)}
+	if opts.registryCredentialsPath != "" {
+		b, err := os.ReadFile(opts.registryCredentialsPath)
+		if err != nil {
+			return registry.Auth{}, fmt.Errorf("failed to read registry credentials: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(b, &auth.Credentials); err != nil {
+			return registry.Auth{}, fmt.Errorf("failed to parse registry credentials %s: %w", opts.registryCredentialsPath, err)
+		}
+	}
+	return auth, nil
+}
+
+// registryTransport returns the registry connections configured by the options
+func registryTransport(opts options) (registry.Transport, error) {
+	transport := registry.Transport{Proxy: opts.registryProxy, NoProxy: opts.registryNoProxy}
+	if opts.registryConfigPath != "" {
+		b, err := os.ReadFile(opts.registryConfigPath)
+		if err != nil {
+			return registry.Transport{}, fmt.Errorf("failed to read registry configuration: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(b, &transport.Registries); err != nil {
+			return registry.Transport{}, fmt.Errorf("failed to parse registry configuration %s: %w", opts.registryConfigPath, err)
+		}
+	}
+	for _, r := range splitList(opts.insecureRegistries) {
+		transport.Registries = append(transport.Registries, registry.Registry{Registry: r, Insecure: true})
+	}
+	return transport, nil
+}
+
+// registryMirrors returns the registry mirrors configured by the options
+func registryMirrors(opts options) (registry.Mirrors, error) {
+	var mirrors registry.Mirrors
+	for _, entry := range splitList(opts.registryMirrors) {
+		prefix, mirror, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid registry mirror %q, must be prefix=mirror", entry)
+		}
+		mirrors = append(mirrors, registry.Mirror{Prefix: strings.TrimSpace(prefix), Mirror: strings.TrimSpace(mirror)})
+	}
+	if err := mirrors.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid registry mirrors: %w", err)
+	}
+	return mirrors, nil
+}
+
+func verify(ctx context.Context, out io.Writer, opts options) (imageverifier.Response, error) {
+	b, err := os.ReadFile(opts.resourcePath)
 	if err != nil {
-		panic(err)
+		return imageverifier.Response{}, fmt.Errorf("failed to read resource: %w", err)
 	}
 
 	var resource interface{}
 	if err := json.Unmarshal(b, &resource); err != nil {
-		panic(err)
+		return imageverifier.Response{}, fmt.Errorf("failed to parse resource %s: %w", opts.resourcePath, err)
 	}
 
-	pol, err := Load(policyPath)
+	pol, err := Load(opts.policyPath)
 	if err != nil {
-		panic(err)
+		return imageverifier.Response{}, fmt.Errorf("failed to load policies: %w", err)
+	}
+
+	var exceptions []*v1alpha1.PolicyException
+	if opts.exceptionPath != "" {
+		exceptions, err = LoadExceptions(opts.exceptionPath)
+		if err != nil {
+			return imageverifier.Response{}, fmt.Errorf("failed to load policy exceptions: %w", err)
+		}
 	}
 
-	verifier := imageverifier.NewEngineFromDClient(nil)
+	engineOpts, err := engineOptions(opts)
+	if err != nil {
+		return imageverifier.Response{}, err
+	}
+	verifier := imageverifier.NewEngineFromDClient(nil, engineOpts...)
 	request := imageverifier.Request{
-		Policies: pol,
-		Resource: resource,
+		Policies:   pol,
+		Exceptions: exceptions,
+		Resource:   resource,
 	}
-	response := verifier.Apply(request)
+	response := verifier.Apply(ctx, request)
 
-	fmt.Fprintln(out, "Verification Result:")
-	for _, p := range response.PolicyResponses {
-		fmt.Fprintf(out, "Results for policy: %s\n", p.Policy.Name)
-		for _, r := range p.RuleResponses {
-			fmt.Fprintf(out, "Results for rule: %s\n", r.Rule.Name)
-			resp := r.VerificationResult
-			fmt.Fprintf(out, "Verifying image: %s, result: %s\n", resp.Image, resp.VerificationOutcome)
-			switch resp.VerificationOutcome {
-			case imageverifier.ERROR:
-				fmt.Fprintf(out, "Error encountered: %v\n", resp.Error)
-			case imageverifier.FAIL:
-				fmt.Fprintf(out, "Failures:\n")
-				for _, vresp := range resp.VerificationResponses {
-					for _, err := range vresp.Failures {
-						fmt.Fprintln(out, err)
-					}
-				}
-			}
-		}
+	if err := report.Write(out, opts.output, response, report.WithResourcePath(opts.resourcePath)); err != nil {
+		return response, fmt.Errorf("failed to write output: %w", err)
 	}
+	return response, nil
 }
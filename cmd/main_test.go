@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStderr string
+	}{{
+		name:       "missing flags",
+		args:       []string{},
+		want:       exitUsage,
+		wantStderr: "usage: verifier",
+	}, {
+		name: "unknown flag",
+		args: []string{"--unknown"},
+		want: exitUsage,
+	}, {
+		name: "invalid output",
+		args: []string{"--policy", "policy.yaml", "--resource", "payload.json", "--output", "xml"},
+		want: exitUsage,
+	}, {
+		name: "invalid fail-on",
+		args: []string{"--policy", "policy.yaml", "--resource", "payload.json", "--fail-on", "pass"},
+		want: exitUsage,
+	}, {
+		name:       "missing resource",
+		args:       []string{"--policy", "./examples/cosign-keyed/policy.yaml", "--resource", "./examples/missing.json"},
+		want:       exitError,
+		wantStderr: "error: failed to read resource: ",
+	}, {
+		name:       "invalid resource",
+		args:       []string{"--policy", "./examples/cosign-keyed/policy.yaml", "--resource", "./examples/cosign-keyed/policy.yaml"},
+		want:       exitError,
+		wantStderr: "error: failed to parse resource ",
+	}, {
+		name:       "missing policy",
+		args:       []string{"--policy", "./examples/missing.yaml", "--resource", "./examples/cosign-keyed/payload.json"},
+		want:       exitError,
+		wantStderr: "error: failed to load policies: ",
+	}, {
+		name:       "invalid credential helper",
+		args:       []string{"--policy", "./examples/cosign-keyed/policy.yaml", "--resource", "./examples/cosign-keyed/payload.json", "--credential-helpers", "google,unknown"},
+		want:       exitError,
+		wantStderr: `error: unsupported credential helper "unknown"`,
+	}, {
+		name:       "missing registry credentials",
+		args:       []string{"--policy", "./examples/cosign-keyed/policy.yaml", "--resource", "./examples/cosign-keyed/payload.json", "--registry-credentials", "./examples/missing.yaml"},
+		want:       exitError,
+		wantStderr: "error: failed to read registry credentials: ",
+	}, {
+		name:       "missing OCI layout",
+		args:       []string{"--policy", "./examples/cosign-keyed/policy.yaml", "--resource", "./examples/cosign-keyed/payload.json", "--oci-layout", "./examples/missing"},
+		want:       exitError,
+		wantStderr: "error: failed to open OCI layout: ",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.want, run(context.Background(), tt.args, &stdout, &stderr))
+			assert.Contains(t, stderr.String(), tt.wantStderr)
+			assert.NotContains(t, stderr.String(), "goroutine")
+		})
+	}
+}
+
+func Test_ExitCode(t *testing.T) {
+	response := func(outcomes ...imageverifier.VerificationOutcome) imageverifier.Response {
+		var rules []imageverifier.RuleResponse
+		for _, o := range outcomes {
+			rules = append(rules, imageverifier.RuleResponse{VerificationOutcome: o})
+		}
+		return imageverifier.Response{PolicyResponses: []imageverifier.PolicyResponse{{RuleResponses: rules}}}
+	}
+	tests := []struct {
+		name     string
+		outcomes []imageverifier.VerificationOutcome
+		failOn   failOn
+		want     int
+	}{
+		{"pass", []imageverifier.VerificationOutcome{imageverifier.PASS}, failOnFail, exitPass},
+		{"no rules", nil, failOnSkip, exitPass},
+		{"fail", []imageverifier.VerificationOutcome{imageverifier.PASS, imageverifier.FAIL}, failOnFail, exitFail},
+		{"fail ignored", []imageverifier.VerificationOutcome{imageverifier.FAIL}, failOnError, exitPass},
+		{"skip ignored", []imageverifier.VerificationOutcome{imageverifier.SKIP}, failOnFail, exitPass},
+		{"skip", []imageverifier.VerificationOutcome{imageverifier.SKIP, imageverifier.PASS}, failOnSkip, exitFail},
+		{"error", []imageverifier.VerificationOutcome{imageverifier.FAIL, imageverifier.ERROR}, failOnFail, exitError},
+		{"error on error", []imageverifier.VerificationOutcome{imageverifier.ERROR}, failOnError, exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exitCode(response(tt.outcomes...), tt.failOn))
+		})
+	}
+}
+
+func Test_ExitCode_Audit(t *testing.T) {
+	response := imageverifier.Response{PolicyResponses: []imageverifier.PolicyResponse{{
+		RuleResponses: []imageverifier.RuleResponse{
+			{VerificationOutcome: imageverifier.FAIL, ValidationFailureAction: v1alpha1.Audit},
+			{VerificationOutcome: imageverifier.ERROR, ValidationFailureAction: v1alpha1.Audit},
+			{VerificationOutcome: imageverifier.PASS, ValidationFailureAction: v1alpha1.Enforce},
+		},
+	}}}
+	assert.Equal(t, exitPass, exitCode(response, failOnFail))
+	response.PolicyResponses[0].RuleResponses[2].VerificationOutcome = imageverifier.FAIL
+	assert.Equal(t, exitFail, exitCode(response, failOnFail))
+}
+
+func Test_Serve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitUsage, run(ctx, []string{"serve"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(ctx, []string{"serve", "--policy", "./examples/cosign-keyed/policy.yaml", "--cluster"}, &stdout, &stderr))
+	assert.Equal(t, exitError, run(ctx, []string{"serve", "--policy", "./examples/missing.yaml"}, &stdout, &stderr))
+	// the server shuts down immediately as the context is already canceled
+	assert.Equal(t, exitPass, run(ctx, []string{"serve", "--policy", "./examples/cosign-keyed/policy.yaml", "--addr", "127.0.0.1:0"}, &stdout, &stderr))
+}
+
+func Test_Webhook(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var stdout, stderr bytes.Buffer
+	dir := t.TempDir()
+	assert.Equal(t, exitUsage, run(ctx, []string{"webhook"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(ctx, []string{"webhook", "--cert-dir", dir, "--register"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(ctx, []string{"webhook", "--cert-dir", dir, "--self-signed"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(ctx, []string{"webhook", "--cert-dir", dir, "--verify-images"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(ctx, []string{"webhook", "--cert-dir", dir, "--policy", "./examples/cosign-keyed/policy.yaml"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(ctx, []string{"webhook", "--cert-dir", dir, "--verify-images", "--cluster", "--exception", "./examples"}, &stdout, &stderr))
+	assert.Equal(t, exitError, run(ctx, []string{"webhook", "--cert-dir", dir, "--addr", "127.0.0.1:0"}, &stdout, &stderr))
+	// the server shuts down immediately as the context is already canceled
+	assert.Equal(t, exitPass, run(ctx, []string{"webhook", "--cert-dir", dir, "--self-signed", "--hosts", "localhost", "--addr", "127.0.0.1:0"}, &stdout, &stderr))
+	assert.FileExists(t, filepath.Join(dir, "ca.crt"))
+	assert.Equal(t, exitPass, run(ctx, []string{"webhook", "--cert-dir", dir, "--verify-images", "--policy", "./examples/cosign-keyed/policy.yaml", "--addr", "127.0.0.1:0"}, &stdout, &stderr))
+}
+
+func Test_Parse_Invalid(t *testing.T) {
+	_, err := Parse([]byte(`apiVersion: nirmata.io/v1alpha1
+kind: ImageVerificationPolicy
+metadata:
+  name: invalid
+spec:
+  rules:
+    - name: duplicate
+      imageExtractors:
+        - path: /containerDefinitions/*/image/
+      count: 2
+      verify:
+      - imageReferences:
+        - ghcr.io/*
+    - name: duplicate
+      imageExtractors:
+        - path: /containerDefinitions/*/image/
+      verify:
+      - imageReferences:
+        - ghcr.io/*
+`))
+	assert.ErrorContains(t, err, "invalid policy invalid: ")
+	assert.ErrorContains(t, err, "spec.rules[0].count: Invalid value: 2")
+	assert.ErrorContains(t, err, `spec.rules[1].name: Duplicate value: "duplicate"`)
+}
+
+func Test_ParseExceptions(t *testing.T) {
+	exceptions, err := ParseExceptions([]byte(`apiVersion: nirmata.io/v1alpha1
+kind: PolicyException
+metadata:
+  name: legacy-images
+spec:
+  exceptions:
+  - policyName: check-images
+    ruleNames:
+    - signed
+  imageReferences:
+  - ghcr.io/nirmata/legacy:*
+  expires: "2030-01-01T00:00:00Z"
+`))
+	assert.NoError(t, err)
+	assert.Len(t, exceptions, 1)
+	assert.Equal(t, "legacy-images", exceptions[0].Name)
+	assert.Equal(t, []string{"ghcr.io/nirmata/legacy:*"}, exceptions[0].Spec.ImageReferences)
+	assert.Equal(t, 2030, exceptions[0].Spec.Expires.Year())
+
+	_, err = ParseExceptions([]byte(`apiVersion: nirmata.io/v1alpha1
+kind: PolicyException
+metadata:
+  name: invalid
+spec:
+  exceptions:
+  - ruleNames:
+    - signed
+`))
+	assert.Error(t, err)
+
+	_, err = ParseExceptions([]byte(`apiVersion: nirmata.io/v1alpha1
+kind: ImageVerificationPolicy
+metadata:
+  name: policy
+spec:
+  rules: []
+`))
+	assert.ErrorContains(t, err, "policy exception type not supported")
+}
+
+func Test_RegistryAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("- registry: ghcr.io\n  token: token\n- registry: registry.example.com:5000\n  username: user\n  password: pass\n"), 0o600))
+
+	auth, err := registryAuth(options{registryCredentialsPath: path, dockerConfig: "config.json", credentialHelpers: "amazon, google"})
+	assert.NoError(t, err)
+	assert.Equal(t, registry.Auth{
+		Credentials: []registry.Credential{
+			{Registry: "ghcr.io", Token: "token"},
+			{Registry: "registry.example.com:5000", Username: "user", Password: "pass"},
+		},
+		DockerConfig:      "config.json",
+		CredentialHelpers: []string{"amazon", "google"},
+	}, auth)
+
+	assert.NoError(t, os.WriteFile(path, []byte("- registry: ghcr.io\n  user: user\n"), 0o600))
+	_, err = registryAuth(options{registryCredentialsPath: path})
+	assert.ErrorContains(t, err, "failed to parse registry credentials")
+}
+
+func Test_RegistryTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registries.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("- registry: harbor.example.com\n  caFile: ca.pem\n  certFile: client.pem\n  keyFile: client-key.pem\n"), 0o600))
+
+	transport, err := registryTransport(options{registryConfigPath: path, insecureRegistries: "localhost:5000, ", registryProxy: "http://proxy:3128", registryNoProxy: ".internal"})
+	assert.NoError(t, err)
+	assert.Equal(t, registry.Transport{
+		Registries: []registry.Registry{
+			{Registry: "harbor.example.com", CAFile: "ca.pem", CertFile: "client.pem", KeyFile: "client-key.pem"},
+			{Registry: "localhost:5000", Insecure: true},
+		},
+		Proxy:   "http://proxy:3128",
+		NoProxy: ".internal",
+	}, transport)
+
+	assert.NoError(t, os.WriteFile(path, []byte("- registry: harbor.example.com\n  ca: ca.pem\n"), 0o600))
+	_, err = registryTransport(options{registryConfigPath: path})
+	assert.ErrorContains(t, err, "failed to parse registry configuration")
+}
+
+func Test_RegistryMirrors(t *testing.T) {
+	mirrors, err := registryMirrors(options{registryMirrors: "docker.io/*=mirror.corp/dockerhub/*, ghcr.io/nirmata/app = mirror.corp/app"})
+	assert.NoError(t, err)
+	assert.Equal(t, registry.Mirrors{
+		{Prefix: "docker.io/*", Mirror: "mirror.corp/dockerhub/*"},
+		{Prefix: "ghcr.io/nirmata/app", Mirror: "mirror.corp/app"},
+	}, mirrors)
+
+	_, err = registryMirrors(options{registryMirrors: "docker.io/*"})
+	assert.ErrorContains(t, err, `invalid registry mirror "docker.io/*", must be prefix=mirror`)
+	_, err = registryMirrors(options{registryMirrors: "docker.io/*=mirror.corp"})
+	assert.ErrorContains(t, err, "invalid registry mirrors: prefix docker.io/* and mirror mirror.corp must both or neither end with *")
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/client/clientset/versioned"
+	"github.com/nirmata/json-image-verification/pkg/client/informers/externalversions"
+	informers "github.com/nirmata/json-image-verification/pkg/client/informers/externalversions/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/controller"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/nirmata/json-image-verification/pkg/policycache"
+	verifyserver "github.com/nirmata/json-image-verification/pkg/server"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// serve runs the verification server until ctx is done and returns the process exit code
+func serve(ctx context.Context, args []string, stderr io.Writer) int {
+	var opts options
+	var addr string
+	var shutdownTimeout time.Duration
+	var cluster bool
+	var kubeconfig string
+	var updateStatus bool
+	flags := flag.NewFlagSet("verifier serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addEngineFlags(flags, &opts)
+	flags.StringVar(&addr, "addr", ":8080", "address the server listens on")
+	flags.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "maximum duration to wait for pending requests on shutdown")
+	flags.BoolVar(&cluster, "cluster", false, "load the policies from the cluster and reload them on change instead of --policy")
+	flags.StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig used with --cluster, the in-cluster configuration is used by default")
+	flags.BoolVar(&updateStatus, "update-status", false, "validate the policies of the cluster and write the result in their status, requires --cluster")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitPass
+		}
+		return exitUsage
+	}
+	if (opts.policyPath == "" && !cluster) || (opts.policyPath != "" && cluster) || (opts.exceptionPath != "" && cluster) || (updateStatus && !cluster) || flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "usage: verifier serve (--policy <POLICY> | --cluster) [flags]")
+		flags.PrintDefaults()
+		return exitUsage
+	}
+
+	var policies verifyserver.PolicySource
+	if cluster {
+		c, err := clusterPolicies(ctx, kubeconfig, updateStatus, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		policies = c
+	} else {
+		p, err := staticPolicies(opts, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		policies = p
+	}
+	engine, err := newEngine(ctx, opts, cluster, kubeconfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to listen on %s: %v\n", addr, err)
+		return exitError
+	}
+
+	fmt.Fprintf(stderr, "serving on %s\n", listener.Addr())
+	s := verifyserver.New(engine, policies)
+	if err := s.Run(ctx, listener, shutdownTimeout); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return exitPass
+}
+
+// newEngine creates the engine configured by the options. The engine of a cluster reads the image
+// pull secrets of the policies from the cluster.
+func newEngine(ctx context.Context, opts options, cluster bool, kubeconfig string) (verifyserver.Engine, error) {
+	engineOpts, err := engineOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if !cluster {
+		return imageverifier.NewEngineFromDClient(nil, engineOpts...), nil
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes configuration: %w", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return imageverifier.NewEngine(ctx, kubeClient, dynamicClient, engineOpts...)
+}
+
+// staticPolicies loads the policies and the policy exceptions of the files given by the options
+func staticPolicies(opts options, out io.Writer) (verifyserver.PolicySource, error) {
+	p, err := Load(opts.policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies: %w", err)
+	}
+	fmt.Fprintf(out, "loaded %d policies\n", len(p))
+	if opts.exceptionPath == "" {
+		return verifyserver.StaticPolicies(p), nil
+	}
+	e, err := LoadExceptions(opts.exceptionPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policy exceptions: %w", err)
+	}
+	fmt.Fprintf(out, "loaded %d policy exceptions\n", len(e))
+	return verifyserver.WithExceptions(verifyserver.StaticPolicies(p), verifyserver.StaticExceptions(e)), nil
+}
+
+// clusterExceptions provides the valid policy exceptions of the cluster from the informer cache
+type clusterExceptions struct {
+	informer informers.PolicyExceptionInformer
+}
+
+func (e clusterExceptions) Exceptions() []*v1alpha1.PolicyException {
+	// listing the informer cache never fails
+	exceptions, _ := e.informer.Lister().List(labels.Everything())
+	valid := make([]*v1alpha1.PolicyException, 0, len(exceptions))
+	for _, exception := range exceptions {
+		if len(exception.Validate()) == 0 {
+			valid = append(valid, exception)
+		}
+	}
+	sort.Slice(valid, func(i, j int) bool {
+		return valid[i].Name < valid[j].Name
+	})
+	return valid
+}
+
+func (e clusterExceptions) HasSynced() bool {
+	return e.informer.Informer().HasSynced()
+}
+
+// clusterPolicies starts watching the policies and the policy exceptions of the cluster, the changes
+// of the policies are logged to out. When updateStatus is true the validation of the policies is
+// also written in their status.
+func clusterPolicies(ctx context.Context, kubeconfig string, updateStatus bool, out io.Writer) (verifyserver.PolicySource, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes configuration: %w", err)
+	}
+	client, err := versioned.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	factory := externalversions.NewSharedInformerFactory(client, 0)
+	informer := factory.Nirmata().V1alpha1().ImageVerificationPolicies()
+	c, err := policycache.New(informer)
+	if err != nil {
+		return nil, err
+	}
+	exceptions := clusterExceptions{informer: factory.Nirmata().V1alpha1().PolicyExceptions()}
+	// the informer is registered in the factory before it is started
+	exceptions.informer.Informer()
+	if updateStatus {
+		statusController, err := controller.NewStatusController(client, informer)
+		if err != nil {
+			return nil, err
+		}
+		go statusController.Run(ctx, 1)
+	}
+	c.AddHandler(func(e policycache.Event) {
+		if e.Err != nil {
+			fmt.Fprintf(out, "policy %s failed to load: %v\n", e.Name, e.Err)
+			return
+		}
+		fmt.Fprintf(out, "policy %s %s\n", e.Name, strings.ToLower(string(e.Type)))
+	})
+	factory.Start(ctx.Done())
+	return verifyserver.WithExceptions(c, exceptions), nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	verifyserver "github.com/nirmata/json-image-verification/pkg/server"
+	"github.com/nirmata/json-image-verification/pkg/webhook"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// webhookOptions are the options of the webhook command
+type webhookOptions struct {
+	addr             string
+	shutdownTimeout  time.Duration
+	certDir          string
+	selfSigned       bool
+	hosts            string
+	serviceName      string
+	serviceNamespace string
+	url              string
+	register         bool
+	kubeconfig       string
+	verifyImages     bool
+	cluster          bool
+	verifyResources  string
+	engine           options
+}
+
+// webhookCommand runs the admission webhook server until ctx is done and returns the process exit code
+func webhookCommand(ctx context.Context, args []string, stderr io.Writer) int {
+	var opts webhookOptions
+	flags := flag.NewFlagSet("verifier webhook", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.addr, "addr", ":9443", "address the webhook listens on")
+	flags.DurationVar(&opts.shutdownTimeout, "shutdown-timeout", 30*time.Second, "maximum duration to wait for pending requests on shutdown")
+	flags.StringVar(&opts.certDir, "cert-dir", "", "directory containing the tls.crt and tls.key serving certificate, and ca.crt when registering the webhook")
+	flags.BoolVar(&opts.selfSigned, "self-signed", false, "generate a self-signed CA and serving certificate in --cert-dir unless the ones of a previous run are still valid, intended for local clusters such as kind")
+	flags.StringVar(&opts.hosts, "hosts", "", "comma separated DNS names and IP addresses of the self-signed certificate in addition to the service and URL hosts")
+	flags.StringVar(&opts.serviceName, "service-name", "", "name of the service exposing the webhook in the cluster")
+	flags.StringVar(&opts.serviceNamespace, "service-namespace", "default", "namespace of the service exposing the webhook in the cluster, its objects are never sent to the image verification webhook")
+	flags.StringVar(&opts.url, "url", "", "URL the API server calls the webhook on when it runs outside of the cluster, instead of --service-name")
+	flags.BoolVar(&opts.register, "register", false, "create or update the validating webhook configuration of the policies in the cluster")
+	flags.StringVar(&opts.kubeconfig, "kubeconfig", "", "path to the kubeconfig used with --register and --cluster, the in-cluster configuration is used by default")
+	flags.BoolVar(&opts.verifyImages, "verify-images", false, "also verify the images of the admitted objects with the policies of --policy or --cluster")
+	flags.BoolVar(&opts.cluster, "cluster", false, "load the policies used with --verify-images from the cluster and reload them on change instead of --policy")
+	flags.StringVar(&opts.verifyResources, "verify-resources", "v1/pods", "comma separated [group/]version/resource of the objects sent to the image verification webhook by --register")
+	addEngineFlags(flags, &opts.engine)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitPass
+		}
+		return exitUsage
+	}
+	usage := opts.certDir == "" || (opts.register && (opts.serviceName == "") == (opts.url == "")) || flags.NArg() > 0
+	if opts.verifyImages {
+		usage = usage || (opts.engine.policyPath == "") == !opts.cluster || (opts.engine.exceptionPath != "" && opts.cluster)
+	} else {
+		usage = usage || opts.engine.policyPath != "" || opts.engine.exceptionPath != "" || opts.cluster
+	}
+	if usage {
+		fmt.Fprintln(stderr, "usage: verifier webhook --cert-dir <DIR> [--self-signed] [--register (--service-name <NAME> | --url <URL>)] [--verify-images (--policy <POLICY> | --cluster)] [flags]")
+		flags.PrintDefaults()
+		return exitUsage
+	}
+
+	if opts.selfSigned {
+		hosts, err := opts.certificateHosts()
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		_, generated, err := webhook.LoadOrGenerateCertificates(opts.certDir, hosts, 365*24*time.Hour)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		if generated {
+			fmt.Fprintf(stderr, "generated self-signed certificate for %s in %s\n", strings.Join(hosts, ", "), opts.certDir)
+		} else {
+			fmt.Fprintf(stderr, "using self-signed certificate of %s\n", opts.certDir)
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(filepath.Join(opts.certDir, webhook.CertFile), filepath.Join(opts.certDir, webhook.KeyFile))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to load serving certificate: %v\n", err)
+		return exitError
+	}
+	var webhookOpts []webhook.Option
+	if opts.verifyImages {
+		verifier, err := imageVerifier(ctx, opts, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		webhookOpts = append(webhookOpts, webhook.WithImageVerifier(verifier))
+	}
+	if opts.register {
+		if err := registerWebhooks(ctx, opts, stderr); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	}
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to listen on %s: %v\n", opts.addr, err)
+		return exitError
+	}
+
+	fmt.Fprintf(stderr, "serving webhooks on %s\n", listener.Addr())
+	tlsListener := tls.NewListener(listener, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err := webhook.New(webhookOpts...).Run(ctx, tlsListener, opts.shutdownTimeout); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return exitPass
+}
+
+// certificateHosts returns the hosts the serving certificate must be valid for
+func (o webhookOptions) certificateHosts() ([]string, error) {
+	var hosts []string
+	if o.serviceName != "" {
+		service := o.serviceName + "." + o.serviceNamespace + ".svc"
+		hosts = append(hosts, service, service+".cluster.local")
+	}
+	if o.url != "" {
+		u, err := url.Parse(o.url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook URL: %w", err)
+		}
+		hosts = append(hosts, u.Hostname())
+	}
+	for _, host := range strings.Split(o.hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, errors.New("--self-signed requires --service-name, --url or --hosts")
+	}
+	return hosts, nil
+}
+
+// imageVerifier creates the image verifier of the webhook with the policies of the file or of the cluster
+func imageVerifier(ctx context.Context, opts webhookOptions, out io.Writer) (*webhook.ImageVerifier, error) {
+	var policies verifyserver.PolicySource
+	if opts.cluster {
+		c, err := clusterPolicies(ctx, opts.kubeconfig, false, out)
+		if err != nil {
+			return nil, err
+		}
+		policies = c
+	} else {
+		p, err := staticPolicies(opts.engine, out)
+		if err != nil {
+			return nil, err
+		}
+		policies = p
+	}
+	engine, err := newEngine(ctx, opts.engine, opts.cluster, opts.kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return webhook.NewImageVerifier(engine, policies), nil
+}
+
+// registerWebhooks creates or updates the webhook configurations with the CA of the certificate directory
+func registerWebhooks(ctx context.Context, opts webhookOptions, out io.Writer) error {
+	caBundle, err := os.ReadFile(filepath.Join(opts.certDir, webhook.CAFile))
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	var rules []admissionregistrationv1.Rule
+	if opts.verifyImages {
+		for _, resource := range strings.Split(opts.verifyResources, ",") {
+			rule, err := webhook.ParseRule(strings.TrimSpace(resource))
+			if err != nil {
+				return err
+			}
+			rules = append(rules, rule)
+		}
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", opts.kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to load kubernetes configuration: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	configurations := []*admissionregistrationv1.ValidatingWebhookConfiguration{
+		webhook.PolicyWebhookConfiguration(opts.clientConfig(webhook.PolicyValidationPath, caBundle)),
+	}
+	if opts.verifyImages {
+		configurations = append(configurations, webhook.ImageWebhookConfiguration(opts.clientConfig(webhook.ImageVerificationPath, caBundle), rules))
+	}
+	for _, c := range configurations {
+		if err := webhook.Register(ctx, client, c); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "registered webhook %s\n", c.Name)
+	}
+	return nil
+}
+
+// clientConfig returns how the API server calls the webhook served on path
+func (o webhookOptions) clientConfig(path string, caBundle []byte) admissionregistrationv1.WebhookClientConfig {
+	clientConfig := admissionregistrationv1.WebhookClientConfig{CABundle: caBundle}
+	if o.url != "" {
+		u := strings.TrimSuffix(o.url, "/") + path
+		clientConfig.URL = &u
+	} else {
+		clientConfig.Service = &admissionregistrationv1.ServiceReference{
+			Name:      o.serviceName,
+			Namespace: o.serviceNamespace,
+			Path:      &path,
+		}
+	}
+	return clientConfig
+}
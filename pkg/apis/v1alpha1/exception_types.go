@@ -0,0 +1,87 @@
+package v1alpha1
+
+import (
+	"github.com/kyverno/kyverno-json/pkg/apis/policy/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:storageversion
+
+// PolicyException waives the verification failures of images for a limited time
+type PolicyException struct {
+	metav1.TypeMeta `json:",inline" yaml:",inline"`
+
+	// Standard object's metadata.
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	// PolicyException spec.
+	Spec PolicyExceptionSpec `json:"spec" yaml:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PolicyExceptionList is a list of PolicyException instances.
+type PolicyExceptionList struct {
+	metav1.TypeMeta `json:",inline" yaml:",inline"`
+	metav1.ListMeta `json:"metadata" yaml:"metadata"`
+	Items           []PolicyException `json:"items" yaml:"items"`
+}
+
+// PolicyExceptionSpec defines the verification failures waived by an exception. A failure is
+// waived when the policy and rule, the image and the resource all match the exception.
+type PolicyExceptionSpec struct {
+	// Exceptions are the policies and rules the exception applies to.
+	Exceptions []Exception `json:"exceptions" yaml:"exceptions"`
+
+	// ImageReferences is a list of image reference patterns the exception applies to, all the
+	// images of the matching resources are excepted when empty. Wildcards ('*' and '?') are allowed.
+	// +optional
+	ImageReferences []string `json:"imageReferences,omitempty" yaml:"imageReferences,omitempty"`
+
+	// Match defines the resources the exception applies to, all the resources are excepted when
+	// not set.
+	// +optional
+	Match *v1alpha1.Match `json:"match,omitempty" yaml:"match,omitempty"`
+
+	// Expires is the time after which the exception no longer applies, it is required so that
+	// waivers are time-boxed. Long-lived waivers set a distant expiry explicitly.
+	Expires *metav1.Time `json:"expires" yaml:"expires"`
+}
+
+// Exception names a policy and the rules of the policy an exception applies to
+type Exception struct {
+	// PolicyName is the name of the policy.
+	PolicyName string `json:"policyName" yaml:"policyName"`
+
+	// RuleNames are the names of the rules, all the rules of the policy are excepted when empty.
+	// +optional
+	RuleNames []string `json:"ruleNames,omitempty" yaml:"ruleNames,omitempty"`
+}
+
+// Contains returns true when the exception applies to the rule of the policy
+func (e *Exception) Contains(policy, rule string) bool {
+	if e.PolicyName != policy {
+		return false
+	}
+	if len(e.RuleNames) == 0 {
+		return true
+	}
+	for _, r := range e.RuleNames {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired returns true when the exception expired at the given time, an exception without expiry
+// is invalid and treated as expired so that it never becomes a permanent waiver
+func (s *PolicyExceptionSpec) IsExpired(now metav1.Time) bool {
+	return s.Expires == nil || !now.Before(s.Expires)
+}
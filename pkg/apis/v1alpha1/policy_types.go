@@ -5,6 +5,7 @@ import (
 
 	"github.com/kyverno/kyverno-json/pkg/apis/policy/v1alpha1"
 	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -16,6 +17,7 @@ var errMultipleAttestor = fmt.Errorf("multiple attestor cannot be added in the s
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
 // +kubebuilder:storageversion
+// +kubebuilder:subresource:status
 
 // ImageVerificationPolicy defines rules to verify images used in matching resources
 type ImageVerificationPolicy struct {
@@ -27,6 +29,10 @@ type ImageVerificationPolicy struct {
 
 	// ImageVerificationPolicy spec.
 	Spec ImageVerificationPolicySpec `json:"spec" yaml:"spec"`
+
+	// Status contains the policy runtime data.
+	// +optional
+	Status ImageVerificationPolicyStatus `json:"status,omitempty" yaml:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -39,19 +45,94 @@ type ImageVerificationPolicyList struct {
 }
 
 type ImageVerificationPolicySpec struct {
-	Rules []ImageVerificationRule `json:"rules"`
+	// ValidationFailureAction defines if a verification failure of a rule blocks the resource
+	// (Enforce) or is only reported (Audit). Rules can override it. Defaults to Enforce.
+	// +optional
+	ValidationFailureAction ValidationFailureAction `json:"validationFailureAction,omitempty"`
+	// ImagePullSecrets references the secrets holding the credentials of the registries accessed
+	// when verifying the images of the policy. They take precedence over the credentials of the engine.
+	// +optional
+	ImagePullSecrets []ImagePullSecret       `json:"imagePullSecrets,omitempty"`
+	Rules            []ImageVerificationRule `json:"rules"`
+}
+
+// ImagePullSecret references a Kubernetes secret of type kubernetes.io/dockerconfigjson or
+// kubernetes.io/dockercfg
+type ImagePullSecret struct {
+	// Name is the name of the secret.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the secret.
+	Namespace string `json:"namespace"`
+}
+
+// ValidationFailureAction defines how the verification failures of a rule are handled
+// +kubebuilder:validation:Enum=Audit;Enforce
+type ValidationFailureAction string
+
+const (
+	// Audit reports the verification failures as warnings without blocking the resource
+	Audit ValidationFailureAction = "Audit"
+	// Enforce blocks the resource on verification failures
+	Enforce ValidationFailureAction = "Enforce"
+)
+
+// ValidationFailureActions lists the supported validation failure actions
+var ValidationFailureActions = []ValidationFailureAction{Audit, Enforce}
+
+// GetValidationFailureAction returns the validation failure action of the rule, the action of
+// the rule takes precedence over the action of the policy
+func (s *ImageVerificationPolicySpec) GetValidationFailureAction(rule *ImageVerificationRule) ValidationFailureAction {
+	if rule != nil && rule.ValidationFailureAction != "" {
+		return rule.ValidationFailureAction
+	}
+	if s.ValidationFailureAction != "" {
+		return s.ValidationFailureAction
+	}
+	return Enforce
+}
+
+const (
+	// PolicyConditionReady is true when the policy is valid and used to verify images
+	PolicyConditionReady = "Ready"
+	// PolicyConditionInvalid is true when the policy failed validation and is not used to verify images
+	PolicyConditionInvalid = "Invalid"
+)
+
+// ImageVerificationPolicyStatus is the observed state of an ImageVerificationPolicy
+type ImageVerificationPolicyStatus struct {
+	// Conditions are the Ready and Invalid conditions of the policy.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the policy the conditions were computed from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty" yaml:"observedGeneration,omitempty"`
 }
 
 type ImageVerificationRule struct {
 	Name string `json:"name"`
 	// +optional
-	Match          v1alpha1.Match        `json:"match"`
+	Match v1alpha1.Match `json:"match"`
+	// Exclude defines the resources the rule does not apply to, using the same assertion trees
+	// as Match. Resources matching Exclude are skipped even when they match Match.
+	// +optional
+	Exclude        *v1alpha1.Match       `json:"exclude,omitempty"`
 	ImageExtractor ImageExtractorConfigs `json:"imageExtractors"`
 	// +optional
 	Context *[]ContextEntry `json:"context,omitempty"`
 	// +optional
-	RequiredCount int               `json:"count"`
-	Rules         VerificationRules `json:"verify"`
+	RequiredCount int `json:"count"`
+	// Timeout is the maximum duration allowed to verify all the images matched by the rule.
+	// When the timeout expires the rule and its pending images report an ERROR outcome.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// ValidationFailureAction overrides the validation failure action of the policy for the rule.
+	// +optional
+	ValidationFailureAction ValidationFailureAction `json:"validationFailureAction,omitempty"`
+	Rules                   VerificationRules       `json:"verify"`
 }
 
 // ContextEntry adds variables and data sources to a rule Context. Either a
@@ -107,6 +188,12 @@ type VerificationRule struct {
 	// address, repository, image, and tag (defaults to latest). Wildcards ('*' and '?') are allowed.
 	ImageReferences []string `json:"imageReferences"`
 
+	// SkipImageReferences is a list of image reference patterns exempted from the rule, e.g. known
+	// base images. Images matching one of the patterns are not verified by the rule even when they
+	// match ImageReferences. Wildcards ('*' and '?') are allowed.
+	// +optional
+	SkipImageReferences []string `json:"skipImageReferences,omitempty"`
+
 	// Cosign is an array of attributes used to verify cosign signatures
 	// +optional
 	Cosign []*Cosign `json:"cosign,omitempty"`
@@ -118,6 +205,39 @@ type VerificationRule struct {
 	// ExternalService is an array of attributes used to verify image signatures using API call
 	// +optional
 	ExternalService []*ExternalService `json:"externalService,omitempty"`
+
+	// Custom is an array of attestors registered with the engine by type name
+	// +optional
+	Custom []*CustomAttestor `json:"custom,omitempty"`
+
+	// Timeout is the maximum duration allowed for each attestor in this rule to verify an image.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// MutateDigest enables replacing the tag of the verified images with their digest in the
+	// response patch. Images extracted using a JMESPath expression are never mutated.
+	// +optional
+	MutateDigest bool `json:"mutateDigest,omitempty"`
+
+	// RequireDigest fails the verification of images that are not referenced by digest.
+	// +optional
+	RequireDigest bool `json:"requireDigest,omitempty"`
+
+	// VerifyDigest checks that the tag and the digest of images referenced by both resolve to the
+	// same manifest in the registry.
+	// +optional
+	VerifyDigest bool `json:"verifyDigest,omitempty"`
+}
+
+// CustomAttestor references an attestor registered with the engine by type name
+type CustomAttestor struct {
+	// Type is the name the attestor is registered with
+	Type string `json:"type"`
+
+	// Config is the configuration of the attestor, it is passed as is to the attestor
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Config *apiextv1.JSON `json:"config,omitempty"`
 }
 
 // Cosign is a set of attributes used to verify cosign signatures
@@ -144,6 +264,25 @@ type Cosign struct {
 	TSACertChain string `json:"tsaCertChain"`
 	// +optional
 	InToToAttestations []*Attestation `json:"intotoAttestations,omitempty"`
+	// Bundle verifies the Sigstore bundles attached to the image as OCI referrers with the trusted
+	// root, instead of the signatures and attestations stored by cosign in the image repository
+	// +optional
+	Bundle bool `json:"bundle,omitempty"`
+	// TrustedRoot is the Sigstore trusted root used to verify the bundles offline
+	// +optional
+	TrustedRoot *TrustedRoot `json:"trustedRoot,omitempty"`
+}
+
+// TrustedRoot is a Sigstore trusted root (trustedRoot.json) holding the certificate authorities,
+// the transparency logs, the certificate transparency logs and the timestamp authorities trusted
+// to verify bundles. It is supplied either inline or by file.
+type TrustedRoot struct {
+	// Data is the JSON encoding of the trusted root
+	// +optional
+	Data string `json:"data,omitempty"`
+	// File is the path of the trusted root file
+	// +optional
+	File string `json:"file,omitempty"`
 }
 
 type Key struct {
@@ -202,6 +341,9 @@ type Attestation struct {
 	Conditions []kyvernov1.AnyAllConditions `json:"conditions,omitempty" yaml:"conditions,omitempty"`
 }
 
+// Validate returns errMultipleAttestor when a cosign entry of the rule sets more than one attestor,
+// it is kept for the callers of the rule alone. Policies are validated with
+// ImageVerificationPolicy.Validate, which reports this error among the others with its field path.
 func (v *VerificationRule) Validate() error {
 	for _, v := range v.Cosign {
 		if v != nil {
@@ -0,0 +1,319 @@
+package v1alpha1
+
+import (
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// imageReferencePattern matches the characters allowed in image references and wildcards
+var imageReferencePattern = regexp.MustCompile(`^[a-zA-Z0-9._\-/:@*?]+$`)
+
+// Validate returns the errors preventing the policy from being used to verify images. Values
+// containing variables are only known at verification time and are not checked.
+func (p *ImageVerificationPolicy) Validate() field.ErrorList {
+	return p.Spec.Validate(field.NewPath("spec"))
+}
+
+func (s *ImageVerificationPolicySpec) Validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateFailureAction(path.Child("validationFailureAction"), s.ValidationFailureAction)...)
+	for i, secret := range s.ImagePullSecrets {
+		secretPath := path.Child("imagePullSecrets").Index(i)
+		if secret.Name == "" {
+			errs = append(errs, field.Required(secretPath.Child("name"), "secret name is required"))
+		}
+		if secret.Namespace == "" {
+			errs = append(errs, field.Required(secretPath.Child("namespace"), "secret namespace is required"))
+		}
+	}
+	names := map[string]bool{}
+	for i := range s.Rules {
+		rulePath := path.Child("rules").Index(i)
+		rule := &s.Rules[i]
+		if names[rule.Name] {
+			errs = append(errs, field.Duplicate(rulePath.Child("name"), rule.Name))
+		}
+		names[rule.Name] = true
+		errs = append(errs, rule.Validate(rulePath)...)
+	}
+	return errs
+}
+
+// Validate returns the errors preventing the exception from waiving verification failures
+func (e *PolicyException) Validate() field.ErrorList {
+	return e.Spec.Validate(field.NewPath("spec"))
+}
+
+func (s *PolicyExceptionSpec) Validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(s.Exceptions) == 0 {
+		errs = append(errs, field.Required(path.Child("exceptions"), "at least one exception is required"))
+	}
+	for i := range s.Exceptions {
+		if s.Exceptions[i].PolicyName == "" {
+			errs = append(errs, field.Required(path.Child("exceptions").Index(i).Child("policyName"), "policy name is required"))
+		}
+	}
+	if s.Expires == nil {
+		errs = append(errs, field.Required(path.Child("expires"), "expiry is required, long-lived exceptions set a distant expiry explicitly"))
+	}
+	for i, ref := range s.ImageReferences {
+		if !imageReferencePattern.MatchString(ref) {
+			errs = append(errs, field.Invalid(path.Child("imageReferences").Index(i), ref, "must be an image reference where '*' and '?' are the only allowed wildcards"))
+		}
+	}
+	return errs
+}
+
+func (r *ImageVerificationRule) Validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if r.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), "rule name is required"))
+	}
+	for i := range r.ImageExtractor {
+		errs = append(errs, r.ImageExtractor[i].Validate(path.Child("imageExtractors").Index(i))...)
+	}
+	if r.Context != nil {
+		for i := range *r.Context {
+			errs = append(errs, (*r.Context)[i].Validate(path.Child("context").Index(i))...)
+		}
+	}
+	if r.Exclude != nil && len(r.Exclude.Any) == 0 && len(r.Exclude.All) == 0 {
+		errs = append(errs, &field.Error{Type: field.ErrorTypeInvalid, Field: path.Child("exclude").String(), BadValue: field.OmitValueType{}, Detail: "either any or all must be set"})
+	}
+	errs = append(errs, validateFailureAction(path.Child("validationFailureAction"), r.ValidationFailureAction)...)
+	if r.RequiredCount < 0 {
+		errs = append(errs, field.Invalid(path.Child("count"), r.RequiredCount, "must not be negative"))
+	} else if r.RequiredCount > len(r.Rules) {
+		errs = append(errs, field.Invalid(path.Child("count"), r.RequiredCount, fmt.Sprintf("must not be greater than the number of verification rules (%d)", len(r.Rules))))
+	}
+	for i := range r.Rules {
+		errs = append(errs, r.Rules[i].validate(path.Child("verify").Index(i))...)
+	}
+	return errs
+}
+
+func (e *ImageExtractorConfig) Validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	var segments []string
+	for _, s := range strings.Split(e.Path, "/") {
+		if s = strings.TrimSpace(s); s != "" {
+			segments = append(segments, s)
+		}
+	}
+	switch {
+	case e.Path == "":
+		errs = append(errs, field.Required(path.Child("path"), "image extractor path is required"))
+	case e.Value == "" && len(segments) == 0:
+		errs = append(errs, field.Invalid(path.Child("path"), e.Path, "must contain the image field when value is not set"))
+	case e.Value == "" && segments[len(segments)-1] == "*":
+		errs = append(errs, field.Invalid(path.Child("path"), e.Path, "the image field cannot be a wildcard"))
+	}
+	errs = append(errs, validateJMESPath(path.Child("jmesPath"), e.JMESPath)...)
+	return errs
+}
+
+func (c *ContextEntry) Validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if c.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), "context entry name is required"))
+	}
+	switch {
+	case c.APICall == nil && c.Variable == nil:
+		errs = append(errs, field.Required(path, "either apiCall or variable must be set"))
+	case c.APICall != nil && c.Variable != nil:
+		errs = append(errs, field.Forbidden(path, "only one of apiCall or variable can be set"))
+	}
+	if c.APICall != nil {
+		errs = append(errs, validateAPICall(path.Child("apiCall"), c.APICall)...)
+	}
+	if c.Variable != nil {
+		errs = append(errs, validateJMESPath(path.Child("variable", "jmesPath"), c.Variable.JMESPath)...)
+	}
+	return errs
+}
+
+func (v *VerificationRule) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(v.ImageReferences) == 0 {
+		errs = append(errs, field.Required(path.Child("imageReferences"), "at least one image reference is required"))
+	}
+	for i, ref := range v.ImageReferences {
+		if !hasVariables(ref) && !imageReferencePattern.MatchString(ref) {
+			errs = append(errs, field.Invalid(path.Child("imageReferences").Index(i), ref, "must be an image reference where '*' and '?' are the only allowed wildcards"))
+		}
+	}
+	for i, ref := range v.SkipImageReferences {
+		if !hasVariables(ref) && !imageReferencePattern.MatchString(ref) {
+			errs = append(errs, field.Invalid(path.Child("skipImageReferences").Index(i), ref, "must be an image reference where '*' and '?' are the only allowed wildcards"))
+		}
+	}
+	for i, cosign := range v.Cosign {
+		if cosign == nil {
+			continue
+		}
+		cosignPath := path.Child("cosign").Index(i)
+		attestors := 0
+		if cosign.Key != nil {
+			attestors++
+			errs = append(errs, validatePEM(cosignPath.Child("key", "publicKey"), cosign.Key.PublicKey, true)...)
+		}
+		if cosign.Keyless != nil {
+			attestors++
+			errs = append(errs, validatePEM(cosignPath.Child("keyless", "root"), cosign.Keyless.Root, false)...)
+		}
+		if cosign.Certificate != nil {
+			attestors++
+			errs = append(errs, validatePEM(cosignPath.Child("certificate", "cert"), cosign.Certificate.Cert, false)...)
+			errs = append(errs, validatePEM(cosignPath.Child("certificate", "certChain"), cosign.Certificate.CertChain, false)...)
+		}
+		if attestors > 1 {
+			errs = append(errs, field.Forbidden(cosignPath, errMultipleAttestor.Error()))
+		}
+		if cosign.Rekor != nil {
+			errs = append(errs, validatePEM(cosignPath.Child("rekor", "pubKey"), cosign.Rekor.PubKey, false)...)
+		}
+		if cosign.CTLog != nil {
+			errs = append(errs, validatePEM(cosignPath.Child("ctlog", "pubKey"), cosign.CTLog.PubKey, false)...)
+		}
+		errs = append(errs, validatePEM(cosignPath.Child("tsaCertChain"), cosign.TSACertChain, false)...)
+		errs = append(errs, validateBundle(cosignPath, cosign)...)
+	}
+	for i, notary := range v.Notary {
+		if notary == nil {
+			continue
+		}
+		errs = append(errs, validatePEM(path.Child("notary").Index(i).Child("certs"), notary.Certs, false)...)
+	}
+	for i, external := range v.ExternalService {
+		if external == nil {
+			continue
+		}
+		externalPath := path.Child("externalService").Index(i)
+		if external.APICall == nil {
+			errs = append(errs, field.Required(externalPath.Child("apiCall"), "apiCall is required"))
+			continue
+		}
+		errs = append(errs, validateAPICall(externalPath.Child("apiCall"), external.APICall)...)
+	}
+	for i, custom := range v.Custom {
+		if custom != nil && custom.Type == "" {
+			errs = append(errs, field.Required(path.Child("custom").Index(i).Child("type"), "attestor type is required"))
+		}
+	}
+	return errs
+}
+
+// validateBundle checks the attributes of the Sigstore bundle verification, the trusted root
+// replaces the certificates and public keys of the Sigstore services and the keys are not resolved
+// from references
+func validateBundle(path *field.Path, cosign *Cosign) field.ErrorList {
+	var errs field.ErrorList
+	if !cosign.Bundle {
+		if cosign.TrustedRoot != nil {
+			errs = append(errs, field.Forbidden(path.Child("trustedRoot"), "trustedRoot requires bundle"))
+		}
+		return errs
+	}
+	if cosign.TrustedRoot == nil {
+		errs = append(errs, field.Required(path.Child("trustedRoot"), "trustedRoot is required to verify bundles"))
+	} else if (cosign.TrustedRoot.Data == "") == (cosign.TrustedRoot.File == "") {
+		errs = append(errs, &field.Error{Type: field.ErrorTypeInvalid, Field: path.Child("trustedRoot").String(), BadValue: field.OmitValueType{}, Detail: "exactly one of data or file must be set"})
+	}
+	if cosign.Key == nil && cosign.Keyless == nil {
+		errs = append(errs, field.Required(path, "either key or keyless is required to verify bundles"))
+	}
+	if cosign.Key != nil && strings.Contains(cosign.Key.PublicKey, "://") && !hasVariables(cosign.Key.PublicKey) {
+		errs = append(errs, field.Invalid(path.Child("key", "publicKey"), cosign.Key.PublicKey, "must be a PEM encoded public key to verify bundles"))
+	}
+	if cosign.Keyless != nil {
+		if cosign.Keyless.Root != "" {
+			errs = append(errs, field.Forbidden(path.Child("keyless", "root"), "the trusted root holds the certificate authorities of bundles"))
+		}
+		// the certificate authorities of the trusted root issue certificates to any identity
+		if cosign.Keyless.Issuer == "" {
+			errs = append(errs, field.Required(path.Child("keyless", "issuer"), "issuer is required to verify keyless bundles"))
+		}
+		if cosign.Keyless.Subject == "" {
+			errs = append(errs, field.Required(path.Child("keyless", "subject"), "subject is required to verify keyless bundles"))
+		}
+	}
+	if cosign.Certificate != nil {
+		errs = append(errs, field.Forbidden(path.Child("certificate"), "bundles are verified with a key or keyless"))
+	}
+	if cosign.Rekor != nil {
+		errs = append(errs, field.Forbidden(path.Child("rekor"), "the trusted root holds the transparency logs of bundles"))
+	}
+	if cosign.CTLog != nil {
+		errs = append(errs, field.Forbidden(path.Child("ctlog"), "the trusted root holds the certificate transparency logs of bundles"))
+	}
+	if cosign.TSACertChain != "" {
+		errs = append(errs, field.Forbidden(path.Child("tsaCertChain"), "the trusted root holds the timestamp authorities of bundles"))
+	}
+	return errs
+}
+
+func validateFailureAction(path *field.Path, action ValidationFailureAction) field.ErrorList {
+	if action == "" {
+		return nil
+	}
+	for _, a := range ValidationFailureActions {
+		if action == a {
+			return nil
+		}
+	}
+	supported := make([]string, 0, len(ValidationFailureActions))
+	for _, a := range ValidationFailureActions {
+		supported = append(supported, string(a))
+	}
+	return field.ErrorList{field.NotSupported(path, action, supported)}
+}
+
+func validateAPICall(path *field.Path, call *kyvernov1.ContextAPICall) field.ErrorList {
+	return validateJMESPath(path.Child("jmesPath"), call.JMESPath)
+}
+
+// validatePEM checks that the value only contains PEM blocks, references to keys stored outside
+// of the policy such as k8s:// or KMS URIs are accepted when allowReference is true
+func validatePEM(path *field.Path, value string, allowReference bool) field.ErrorList {
+	value = strings.TrimSpace(value)
+	if value == "" || hasVariables(value) {
+		return nil
+	}
+	if allowReference && !strings.HasPrefix(value, "-----BEGIN") && strings.Contains(value, "://") {
+		return nil
+	}
+	rest := []byte(value)
+	blocks := 0
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		blocks++
+	}
+	if blocks == 0 || len(strings.TrimSpace(string(rest))) > 0 {
+		return field.ErrorList{&field.Error{Type: field.ErrorTypeInvalid, Field: path.String(), BadValue: field.OmitValueType{}, Detail: "invalid PEM data"}}
+	}
+	return nil
+}
+
+func validateJMESPath(path *field.Path, expression string) field.ErrorList {
+	if expression == "" || hasVariables(expression) {
+		return nil
+	}
+	if _, err := parsing.NewParser().Parse(expression); err != nil {
+		return field.ErrorList{field.Invalid(path, expression, fmt.Sprintf("invalid JMESPath expression: %v", err))}
+	}
+	return nil
+}
+
+func hasVariables(value string) bool {
+	return strings.Contains(value, "{{")
+}
@@ -0,0 +1,216 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testPublicKey = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAE8nXRh950IZbRj8Ra/N9sbqOPZrfM
+5/KAQN0/KjHcorm/J5yctVd7iEcnessRQjU917hmKO6JWVGHpDguIyakZA==
+-----END PUBLIC KEY-----`
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func Test_ImageVerificationPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		pullSecrets string
+		rules       string
+		want        []string
+	}{
+		{
+			name:  "valid",
+			rules: `[{"name":"a","imageExtractors":[{"path":"/containers/*/image/","jmesPath":"trim_prefix(@, 'docker://')"}],"context":[{"name":"v","variable":{"jmesPath":"images"}}],"count":1,"verify":[{"imageReferences":["ghcr.io/*:v?"],"cosign":[{"key":{"publicKey":` + quote(testPublicKey) + `}}]},{"imageReferences":["*"]}]}]`,
+		},
+		{
+			name:  "references and variables",
+			rules: `[{"name":"a","imageExtractors":[{"path":"/image/"}],"verify":[{"imageReferences":["{{ registry }}/*"],"cosign":[{"key":{"publicKey":"k8s://default/cosign"}}]},{"imageReferences":["*"],"notary":[{"certs":"{{ certs }}"}]}]}]`,
+		},
+		{
+			name:  "duplicate rule names",
+			rules: `[{"name":"a"},{"name":"a"},{}]`,
+			want:  []string{`spec.rules[1].name: Duplicate value: "a"`, "spec.rules[2].name: Required value"},
+		},
+		{
+			name:  "empty image references",
+			rules: `[{"name":"a","verify":[{"imageReferences":[]}]}]`,
+			want:  []string{"spec.rules[0].verify[0].imageReferences: Required value"},
+		},
+		{
+			name:  "invalid wildcard pattern",
+			rules: `[{"name":"a","verify":[{"imageReferences":["ghcr.io/[a-z]*"," "],"skipImageReferences":["ghcr.io/base:*","docker.io/{a,b}"]}]}]`,
+			want:  []string{"spec.rules[0].verify[0].imageReferences[0]: Invalid value", "spec.rules[0].verify[0].imageReferences[1]: Invalid value", "spec.rules[0].verify[0].skipImageReferences[1]: Invalid value"},
+		},
+		{
+			name:  "invalid PEM",
+			rules: `[{"name":"a","verify":[{"imageReferences":["*"],"cosign":[{"key":{"publicKey":"not a key"}},{"certificate":{"cert":` + quote(testPublicKey+"\ntrailing") + `}}],"notary":[{"certs":"-----BEGIN CERTIFICATE-----"}]}]}]`,
+			want: []string{
+				"spec.rules[0].verify[0].cosign[0].key.publicKey: Invalid value: invalid PEM data",
+				"spec.rules[0].verify[0].cosign[1].certificate.cert: Invalid value: invalid PEM data",
+				"spec.rules[0].verify[0].notary[0].certs: Invalid value: invalid PEM data",
+			},
+		},
+		{
+			name:  "multiple attestors",
+			rules: `[{"name":"a","verify":[{"imageReferences":["*"],"cosign":[{"key":{"publicKey":""},"keyless":{}}]}]}]`,
+			want:  []string{"spec.rules[0].verify[0].cosign[0]: Forbidden: multiple attestor cannot be added in the same entry"},
+		},
+		{
+			name:  "count greater than verification rules",
+			rules: `[{"name":"a","count":2,"verify":[{"imageReferences":["*"]}]}]`,
+			want:  []string{"spec.rules[0].count: Invalid value: 2: must not be greater than the number of verification rules (1)"},
+		},
+		{
+			name:  "bad extractor paths",
+			rules: `[{"name":"a","imageExtractors":[{"path":""},{"path":"/"},{"path":"/containers/*"},{"path":"/","value":"image"}]}]`,
+			want: []string{
+				"spec.rules[0].imageExtractors[0].path: Required value",
+				`spec.rules[0].imageExtractors[1].path: Invalid value: "/": must contain the image field when value is not set`,
+				`spec.rules[0].imageExtractors[2].path: Invalid value: "/containers/*": the image field cannot be a wildcard`,
+			},
+		},
+		{
+			name:  "JMESPath syntax errors",
+			rules: `[{"name":"a","imageExtractors":[{"path":"/image/","jmesPath":"trim_prefix(@"}],"context":[{"name":"v","variable":{"jmesPath":"a.["}}],"verify":[{"imageReferences":["*"],"externalService":[{"apiCall":{"service":{"url":"http://localhost"},"jmesPath":"]"}}]}]}]`,
+			want: []string{
+				"spec.rules[0].imageExtractors[0].jmesPath: Invalid value",
+				"spec.rules[0].context[0].variable.jmesPath: Invalid value",
+				"spec.rules[0].verify[0].externalService[0].apiCall.jmesPath: Invalid value",
+			},
+		},
+		{
+			name:  "context entries",
+			rules: `[{"name":"a","context":[{"name":"empty"},{"variable":{"value":"x"}}]}]`,
+			want:  []string{"spec.rules[0].context[0]: Required value: either apiCall or variable must be set", "spec.rules[0].context[1].name: Required value"},
+		},
+		{
+			name:  "empty exclude",
+			rules: `[{"name":"a","exclude":{}},{"name":"b","exclude":{"any":[{"family":"legacy"}]}}]`,
+			want:  []string{"spec.rules[0].exclude: Invalid value: either any or all must be set"},
+		},
+		{
+			name:  "validation failure action",
+			rules: `[{"name":"a","validationFailureAction":"Warn"},{"name":"b","validationFailureAction":"Audit"}]`,
+			want:  []string{`spec.rules[0].validationFailureAction: Unsupported value: "Warn"`},
+		},
+		{
+			name:  "custom attestors",
+			rules: `[{"name":"a","verify":[{"imageReferences":["*"],"custom":[{"type":"signing-service","config":{"keyId":"{{ key }}"}},{"config":{}}]}]}]`,
+			want:  []string{"spec.rules[0].verify[0].custom[1].type: Required value"},
+		},
+		{
+			name:  "bundles",
+			rules: `[{"name":"a","verify":[{"imageReferences":["*"],"cosign":[{"bundle":true,"key":{"publicKey":` + quote(testPublicKey) + `},"trustedRoot":{"file":"/etc/sigstore/trusted_root.json"}},{"bundle":true,"keyless":{"issuer":"https://token.actions.githubusercontent.com","subject":"https://github.com/*"},"trustedRoot":{"data":"{{ trustedRoot }}"}}]}]}]`,
+		},
+		{
+			name:  "invalid bundles",
+			rules: `[{"name":"a","verify":[{"imageReferences":["*"],"cosign":[{"bundle":true},{"bundle":true,"key":{"publicKey":"k8s://default/cosign"},"rekor":{"url":"https://rekor.sigstore.dev"},"trustedRoot":{"data":"{}","file":"trusted_root.json"}},{"bundle":true,"keyless":{"root":` + quote(testPublicKey) + `},"trustedRoot":{}},{"key":{"publicKey":` + quote(testPublicKey) + `},"trustedRoot":{"file":"trusted_root.json"}}]}]}]`,
+			want: []string{
+				"spec.rules[0].verify[0].cosign[0].trustedRoot: Required value",
+				"spec.rules[0].verify[0].cosign[0]: Required value: either key or keyless is required to verify bundles",
+				"spec.rules[0].verify[0].cosign[1].trustedRoot: Invalid value",
+				"spec.rules[0].verify[0].cosign[1].key.publicKey: Invalid value",
+				"spec.rules[0].verify[0].cosign[1].rekor: Forbidden",
+				"spec.rules[0].verify[0].cosign[2].trustedRoot: Invalid value",
+				"spec.rules[0].verify[0].cosign[2].keyless.root: Forbidden",
+				"spec.rules[0].verify[0].cosign[2].keyless.issuer: Required value",
+				"spec.rules[0].verify[0].cosign[2].keyless.subject: Required value",
+				"spec.rules[0].verify[0].cosign[3].trustedRoot: Forbidden: trustedRoot requires bundle",
+			},
+		},
+		{
+			name:        "image pull secrets",
+			pullSecrets: `[{"name":"regcred","namespace":"default"},{"name":"regcred"},{"namespace":"default"}]`,
+			rules:       `[{"name":"a"}]`,
+			want:        []string{"spec.imagePullSecrets[1].namespace: Required value", "spec.imagePullSecrets[2].name: Required value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := `"rules":` + tt.rules
+			if tt.pullSecrets != "" {
+				spec = `"imagePullSecrets":` + tt.pullSecrets + `,` + spec
+			}
+			var policy ImageVerificationPolicy
+			if err := json.Unmarshal([]byte(`{"spec":{`+spec+`}}`), &policy); err != nil {
+				t.Fatal(err)
+			}
+			errs := policy.Validate()
+			if len(errs) != len(tt.want) {
+				t.Fatalf("want %d errors, got %v", len(tt.want), errs)
+			}
+			for i, err := range errs {
+				if !strings.HasPrefix(err.Error(), tt.want[i]) {
+					t.Errorf("want error %q, got %q", tt.want[i], err.Error())
+				}
+			}
+		})
+	}
+}
+
+func Test_PolicyExceptionValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want []string
+	}{
+		{
+			name: "valid",
+			spec: `{"exceptions":[{"policyName":"a","ruleNames":["b"]}],"imageReferences":["ghcr.io/legacy/*"],"expires":"2030-01-01T00:00:00Z"}`,
+		},
+		{
+			name: "missing exceptions",
+			spec: `{"exceptions":[],"expires":"2030-01-01T00:00:00Z"}`,
+			want: []string{"spec.exceptions: Required value"},
+		},
+		{
+			name: "missing expiry",
+			spec: `{"exceptions":[{"policyName":"a"}]}`,
+			want: []string{"spec.expires: Required value"},
+		},
+		{
+			name: "missing policy name",
+			spec: `{"exceptions":[{"ruleNames":["b"]}],"imageReferences":["ghcr.io/{legacy}"],"expires":"2030-01-01T00:00:00Z"}`,
+			want: []string{"spec.exceptions[0].policyName: Required value", `spec.imageReferences[0]: Invalid value: "ghcr.io/{legacy}"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exception PolicyException
+			if err := json.Unmarshal([]byte(`{"spec":`+tt.spec+`}`), &exception); err != nil {
+				t.Fatal(err)
+			}
+			errs := exception.Validate()
+			if len(errs) != len(tt.want) {
+				t.Fatalf("want %d errors, got %v", len(tt.want), errs)
+			}
+			for i, err := range errs {
+				if !strings.HasPrefix(err.Error(), tt.want[i]) {
+					t.Errorf("want error %q, got %q", tt.want[i], err.Error())
+				}
+			}
+		})
+	}
+}
+
+func Test_GetValidationFailureAction(t *testing.T) {
+	spec := ImageVerificationPolicySpec{Rules: []ImageVerificationRule{{Name: "inherit"}, {Name: "override", ValidationFailureAction: Audit}}}
+	if got := spec.GetValidationFailureAction(&spec.Rules[0]); got != Enforce {
+		t.Errorf("want default %s, got %s", Enforce, got)
+	}
+	spec.ValidationFailureAction = Audit
+	spec.Rules[1].ValidationFailureAction = Enforce
+	if got := spec.GetValidationFailureAction(&spec.Rules[0]); got != Audit {
+		t.Errorf("want policy action %s, got %s", Audit, got)
+	}
+	if got := spec.GetValidationFailureAction(&spec.Rules[1]); got != Enforce {
+		t.Errorf("want rule action %s, got %s", Enforce, got)
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Entry is the cached result of a verification rule for an image digest
+type Entry struct {
+	// Failures are the messages of the failures returned by the attestors, empty when verification passed
+	Failures []string `json:"failures,omitempty"`
+	// Expires is the time after which the entry must not be used anymore
+	Expires time.Time `json:"expires"`
+}
+
+func (e Entry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && now.After(e.Expires)
+}
+
+// Cache stores verification results keyed by image digest and attestor configuration
+type Cache interface {
+	// Get returns the entry stored under key, if any and not expired
+	Get(key string) (Entry, bool)
+	// Set stores the failures of a verification under key
+	Set(key string, failures []string)
+}
+
+// Key returns the cache key of a verification of the image digest with the given attestor configuration
+func Key(digest string, config interface{}) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(digest))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type tiered []Cache
+
+// NewTiered returns a cache looking up entries in every cache in order, a hit in a later
+// cache is copied to the earlier ones
+func NewTiered(caches ...Cache) Cache {
+	return tiered(caches)
+}
+
+func (t tiered) Get(key string) (Entry, bool) {
+	for i, c := range t {
+		if entry, ok := c.Get(key); ok {
+			for _, earlier := range t[:i] {
+				earlier.Set(key, entry.Failures)
+			}
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+func (t tiered) Set(key string, failures []string) {
+	for _, c := range t {
+		c.Set(key, failures)
+	}
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	config := map[string]string{"publicKey": "key"}
+	k1, err := Key("sha256:aaa", config)
+	assert.NoError(t, err)
+	k2, err := Key("sha256:aaa", config)
+	assert.NoError(t, err)
+	assert.Equal(t, k1, k2)
+	k3, err := Key("sha256:bbb", config)
+	assert.NoError(t, err)
+	assert.NotEqual(t, k1, k3)
+	k4, err := Key("sha256:aaa", map[string]string{"publicKey": "other"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, k1, k4)
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	c := NewLRU(2, time.Minute).(*lru)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", nil)
+	c.Set("b", []string{"invalid signature"})
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+	c.Set("c", nil)
+	_, ok = c.Get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	entry, ok := c.Get("c")
+	assert.True(t, ok)
+	assert.Empty(t, entry.Failures)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "expired entry should not be returned")
+}
+
+func TestDisk(t *testing.T) {
+	now := time.Now()
+	d, err := NewDisk(t.TempDir(), time.Minute)
+	assert.NoError(t, err)
+	c := d.(*disk)
+	c.now = func() time.Time { return now }
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	c.Set("a", []string{"invalid signature"})
+	entry, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"invalid signature"}, entry.Failures)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestTiered(t *testing.T) {
+	memory := NewLRU(10, 0)
+	d, err := NewDisk(t.TempDir(), 0)
+	assert.NoError(t, err)
+	d.Set("a", nil)
+
+	c := NewTiered(memory, d)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+	_, ok = memory.Get("a")
+	assert.True(t, ok, "hit in the disk cache should populate the memory cache")
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type disk struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewDisk returns a cache storing one JSON file per entry in dir, entries expire after ttl
+// (a zero ttl never expires them)
+func NewDisk(dir string, ttl time.Duration) (Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &disk{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}, nil
+}
+
+func (c *disk) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *disk) Get(key string) (Entry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, false
+	}
+	if entry.expired(c.now()) {
+		_ = os.Remove(c.path(key))
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Set writes the entry to a temporary file first so that concurrent readers never see a partial entry,
+// failing to persist an entry only results in a cache miss later
+func (c *disk) Set(key string, failures []string) {
+	entry := Entry{Failures: failures}
+	if c.ttl > 0 {
+		entry.Expires = c.now().Add(c.ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return
+	}
+	if err := f.Close(); err != nil {
+		return
+	}
+	_ = os.Rename(f.Name(), c.path(key))
+}
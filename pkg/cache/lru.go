@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+type lru struct {
+	lock  sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[string]*list.Element
+	order *list.List
+	now   func() time.Time
+}
+
+// NewLRU returns an in-memory cache holding at most size entries, entries expire after ttl
+// (a zero ttl never expires them)
+func NewLRU(size int, ttl time.Duration) Cache {
+	if size <= 0 {
+		size = 1
+	}
+	return &lru{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		now:   time.Now,
+	}
+}
+
+func (c *lru) Get(key string) (Entry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	item := elem.Value.(*lruItem)
+	if item.entry.expired(c.now()) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return Entry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return item.entry, true
+}
+
+func (c *lru) Set(key string, failures []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := Entry{Failures: failures}
+	if c.ttl > 0 {
+		entry.Expires = c.now().Add(c.ttl)
+	}
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
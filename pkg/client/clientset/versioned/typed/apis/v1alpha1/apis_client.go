@@ -29,6 +29,7 @@ import (
 type NirmataV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	ImageVerificationPoliciesGetter
+	PolicyExceptionsGetter
 }
 
 // NirmataV1alpha1Client is used to interact with features provided by the nirmata.io group.
@@ -40,6 +41,10 @@ func (c *NirmataV1alpha1Client) ImageVerificationPolicies() ImageVerificationPol
 	return newImageVerificationPolicies(c)
 }
 
+func (c *NirmataV1alpha1Client) PolicyExceptions() PolicyExceptionInterface {
+	return newPolicyExceptions(c)
+}
+
 // NewForConfig creates a new NirmataV1alpha1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
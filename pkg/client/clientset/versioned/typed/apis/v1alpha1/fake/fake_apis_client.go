@@ -32,6 +32,10 @@ func (c *FakeNirmataV1alpha1) ImageVerificationPolicies() v1alpha1.ImageVerifica
 	return &FakeImageVerificationPolicies{c}
 }
 
+func (c *FakeNirmataV1alpha1) PolicyExceptions() v1alpha1.PolicyExceptionInterface {
+	return &FakePolicyExceptions{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeNirmataV1alpha1) RESTClient() rest.Interface {
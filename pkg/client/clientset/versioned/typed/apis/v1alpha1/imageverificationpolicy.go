@@ -40,6 +40,7 @@ type ImageVerificationPoliciesGetter interface {
 type ImageVerificationPolicyInterface interface {
 	Create(ctx context.Context, imageVerificationPolicy *v1alpha1.ImageVerificationPolicy, opts v1.CreateOptions) (*v1alpha1.ImageVerificationPolicy, error)
 	Update(ctx context.Context, imageVerificationPolicy *v1alpha1.ImageVerificationPolicy, opts v1.UpdateOptions) (*v1alpha1.ImageVerificationPolicy, error)
+	UpdateStatus(ctx context.Context, imageVerificationPolicy *v1alpha1.ImageVerificationPolicy, opts v1.UpdateOptions) (*v1alpha1.ImageVerificationPolicy, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.ImageVerificationPolicy, error)
@@ -128,6 +129,21 @@ func (c *imageVerificationPolicies) Update(ctx context.Context, imageVerificatio
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *imageVerificationPolicies) UpdateStatus(ctx context.Context, imageVerificationPolicy *v1alpha1.ImageVerificationPolicy, opts v1.UpdateOptions) (result *v1alpha1.ImageVerificationPolicy, err error) {
+	result = &v1alpha1.ImageVerificationPolicy{}
+	err = c.client.Put().
+		Resource("imageverificationpolicies").
+		Name(imageVerificationPolicy.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(imageVerificationPolicy).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the imageVerificationPolicy and deletes it. Returns an error if one occurs.
 func (c *imageVerificationPolicies) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -26,6 +26,8 @@ import (
 type Interface interface {
 	// ImageVerificationPolicies returns a ImageVerificationPolicyInformer.
 	ImageVerificationPolicies() ImageVerificationPolicyInformer
+	// PolicyExceptions returns a PolicyExceptionInformer.
+	PolicyExceptions() PolicyExceptionInformer
 }
 
 type version struct {
@@ -43,3 +45,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) ImageVerificationPolicies() ImageVerificationPolicyInformer {
 	return &imageVerificationPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// PolicyExceptions returns a PolicyExceptionInformer.
+func (v *version) PolicyExceptions() PolicyExceptionInformer {
+	return &policyExceptionInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -55,6 +55,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=nirmata.io, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("imageverificationpolicies"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Nirmata().V1alpha1().ImageVerificationPolicies().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("policyexceptions"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Nirmata().V1alpha1().PolicyExceptions().Informer()}, nil
 
 	}
 
@@ -21,3 +21,7 @@ package v1alpha1
 // ImageVerificationPolicyListerExpansion allows custom methods to be added to
 // ImageVerificationPolicyLister.
 type ImageVerificationPolicyListerExpansion interface{}
+
+// PolicyExceptionListerExpansion allows custom methods to be added to
+// PolicyExceptionLister.
+type PolicyExceptionListerExpansion interface{}
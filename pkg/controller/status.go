@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/client/clientset/versioned"
+	informers "github.com/nirmata/json-image-verification/pkg/client/informers/externalversions/apis/v1alpha1"
+	listers "github.com/nirmata/json-image-verification/pkg/client/listers/apis/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// ReasonValid is the reason of the conditions of a valid policy
+	ReasonValid = "Valid"
+	// ReasonValidationFailed is the reason of the conditions of an invalid policy
+	ReasonValidationFailed = "ValidationFailed"
+)
+
+// StatusController validates the policies of the cluster and reports the result in their status
+type StatusController struct {
+	client versioned.Interface
+	lister listers.ImageVerificationPolicyLister
+	synced cache.InformerSynced
+	queue  workqueue.RateLimitingInterface
+}
+
+// NewStatusController creates a controller reconciling the policies of the informer, the informer
+// must be started by the caller
+func NewStatusController(client versioned.Interface, informer informers.ImageVerificationPolicyInformer) (*StatusController, error) {
+	c := &StatusController{
+		client: client,
+		lister: informer.Lister(),
+		queue:  workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{Name: "policy-status"}),
+	}
+	registration, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(_, obj interface{}) {
+			c.enqueue(obj)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register policy event handler: %w", err)
+	}
+	c.synced = registration.HasSynced
+	return c, nil
+}
+
+// Run reconciles the policies with the given number of workers until ctx is done
+func (c *StatusController) Run(ctx context.Context, workers int) {
+	defer c.queue.ShutDown()
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced) {
+		return
+	}
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.worker, time.Second)
+	}
+	<-ctx.Done()
+}
+
+func (c *StatusController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *StatusController) worker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *StatusController) processNextItem(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+	if err := c.reconcile(ctx, key.(string)); err != nil {
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *StatusController) reconcile(ctx context.Context, name string) error {
+	p, err := c.lister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	status := Status(p)
+	if equality.Semantic.DeepEqual(p.Status, status) {
+		return nil
+	}
+	p = p.DeepCopy()
+	p.Status = status
+	_, err = c.client.NirmataV1alpha1().ImageVerificationPolicies().UpdateStatus(ctx, p, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Status returns the status of the policy according to its validation, the transition time of
+// the conditions is kept when their status does not change
+func Status(p *v1alpha1.ImageVerificationPolicy) v1alpha1.ImageVerificationPolicyStatus {
+	status := *p.Status.DeepCopy()
+	status.ObservedGeneration = p.Generation
+	ready := metav1.Condition{
+		Type:               v1alpha1.PolicyConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: p.Generation,
+		Reason:             ReasonValid,
+		Message:            "policy is ready",
+	}
+	invalid := metav1.Condition{
+		Type:               v1alpha1.PolicyConditionInvalid,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: p.Generation,
+		Reason:             ReasonValid,
+		Message:            "policy is valid",
+	}
+	if err := p.Validate().ToAggregate(); err != nil {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = ReasonValidationFailed
+		ready.Message = "policy is invalid"
+		invalid.Status = metav1.ConditionTrue
+		invalid.Reason = ReasonValidationFailed
+		invalid.Message = err.Error()
+	}
+	meta.SetStatusCondition(&status.Conditions, ready)
+	meta.SetStatusCondition(&status.Conditions, invalid)
+	return status
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/client/clientset/versioned/fake"
+	"github.com/nirmata/json-image-verification/pkg/client/informers/externalversions"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPolicy(name string, verify ...v1alpha1.VerificationRule) *v1alpha1.ImageVerificationPolicy {
+	return &v1alpha1.ImageVerificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 2},
+		Spec: v1alpha1.ImageVerificationPolicySpec{
+			Rules: []v1alpha1.ImageVerificationRule{{Name: "rule", Rules: verify}},
+		},
+	}
+}
+
+func Test_Status(t *testing.T) {
+	status := Status(newPolicy("valid"))
+	assert.Equal(t, int64(2), status.ObservedGeneration)
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, v1alpha1.PolicyConditionReady))
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, v1alpha1.PolicyConditionInvalid))
+
+	p := newPolicy("invalid", v1alpha1.VerificationRule{
+		ImageReferences: []string{"*"},
+		Cosign:          []*v1alpha1.Cosign{{Key: &v1alpha1.Key{PublicKey: "-----BEGIN PUBLIC KEY-----\nnot a key"}}},
+	})
+	status = Status(p)
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, v1alpha1.PolicyConditionReady))
+	invalid := meta.FindStatusCondition(status.Conditions, v1alpha1.PolicyConditionInvalid)
+	assert.Equal(t, metav1.ConditionTrue, invalid.Status)
+	assert.Equal(t, ReasonValidationFailed, invalid.Reason)
+	assert.Contains(t, invalid.Message, "spec.rules[0].verify[0].cosign[0].key.publicKey: Invalid value: invalid PEM data")
+
+	// the status is stable once written
+	p.Status = status
+	assert.Equal(t, status, Status(p))
+}
+
+func Test_StatusController(t *testing.T) {
+	client := fake.NewSimpleClientset(newPolicy("valid"))
+	factory := externalversions.NewSharedInformerFactory(client, 0)
+	c, err := NewStatusController(client, factory.Nirmata().V1alpha1().ImageVerificationPolicies())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	go c.Run(ctx, 1)
+
+	assert.Eventually(t, func() bool {
+		p, err := client.NirmataV1alpha1().ImageVerificationPolicies().Get(ctx, "valid", metav1.GetOptions{})
+		return err == nil && p.Status.ObservedGeneration == 2 && meta.IsStatusConditionTrue(p.Status.Conditions, v1alpha1.PolicyConditionReady)
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -16,4 +16,10 @@ func TestCrds(t *testing.T) {
 		assert.NotNil(t, file)
 		assert.False(t, file.IsDir())
 	}
+	{
+		file, err := fs.Stat(data, "nirmata.io_policyexceptions.yaml")
+		assert.NoError(t, err)
+		assert.NotNil(t, file)
+		assert.False(t, file.IsDir())
+	}
 }
@@ -0,0 +1,98 @@
+package imageverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kyverno/kyverno/pkg/registryclient"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+)
+
+// ErrUnknownAttestor is reported when a verification rule references an attestor type that is not registered
+var ErrUnknownAttestor = errors.New("unknown attestor type")
+
+// Attestor verifies images with an attestation that is not built in the engine, e.g. the
+// signatures of an in-house signing service. Attestors are registered by type name with
+// RegisterAttestor or WithAttestors and referenced by the custom entries of the verification rules.
+type Attestor interface {
+	// Verify returns an error when the image fails the verification, the errors wrapping
+	// ErrVerificationFailed are definitive verdicts that the engine may cache
+	Verify(ctx context.Context, request AttestorRequest) error
+}
+
+// AttestorFunc adapts a function to the Attestor interface
+type AttestorFunc func(ctx context.Context, request AttestorRequest) error
+
+func (f AttestorFunc) Verify(ctx context.Context, request AttestorRequest) error {
+	return f(ctx, request)
+}
+
+// AttestorRequest is the verification of an image by an attestor
+type AttestorRequest struct {
+	// Image is the normalized image reference, it is the reference of the mirror when a registry
+	// mirror rewrites the image
+	Image string
+	// Config is the raw JSON configuration of the custom entry once its variables are substituted,
+	// it is nil when the entry has no configuration
+	Config []byte
+	// Registry is the registry client of the engine, attestors accessing registries use it so that
+	// the registry authentication, connections and backend of the engine apply
+	Registry registryclient.Client
+}
+
+var (
+	attestorsLock sync.RWMutex
+	attestors     = map[string]Attestor{}
+)
+
+// RegisterAttestor registers the attestor of the type for every engine, typically from the init
+// function of the package implementing it. It panics when the type is empty, the attestor is nil
+// or the type is already registered.
+func RegisterAttestor(attestorType string, attestor Attestor) {
+	if attestorType == "" || attestor == nil {
+		panic("imageverifier: RegisterAttestor requires a type and an attestor")
+	}
+	attestorsLock.Lock()
+	defer attestorsLock.Unlock()
+	if _, ok := attestors[attestorType]; ok {
+		panic(fmt.Sprintf("imageverifier: attestor type %q is already registered", attestorType))
+	}
+	attestors[attestorType] = attestor
+}
+
+// WithAttestors registers attestors by type for the engine only, they take precedence over the
+// attestors registered with RegisterAttestor
+func WithAttestors(attestors map[string]Attestor) Option {
+	return func(e *engine) {
+		e.attestors = attestors
+	}
+}
+
+// attestor returns the attestor of the type registered with the engine or for every engine
+func (i *imageVerifier) attestor(attestorType string) (Attestor, bool) {
+	if attestor, ok := i.attestors[attestorType]; ok {
+		return attestor, true
+	}
+	attestorsLock.RLock()
+	defer attestorsLock.RUnlock()
+	attestor, ok := attestors[attestorType]
+	return attestor, ok
+}
+
+func (i *imageVerifier) customVerification(ctx context.Context, pol *v1alpha1.CustomAttestor, image string) error {
+	attestor, ok := i.attestor(pol.Type)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownAttestor, pol.Type)
+	}
+	var config []byte
+	if pol.Config != nil {
+		config = pol.Config.Raw
+	}
+	return attestor.Verify(ctx, AttestorRequest{
+		Image:    image,
+		Config:   config,
+		Registry: i.registry,
+	})
+}
@@ -0,0 +1,130 @@
+package imageverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/cache"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Apply_CustomAttestors(t *testing.T) {
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"ghcr.io/org/app:v1"}]}`), &resource)
+	assert.NoError(t, err)
+
+	var requests []AttestorRequest
+	signingService := AttestorFunc(func(_ context.Context, request AttestorRequest) error {
+		requests = append(requests, request)
+		var config struct {
+			Team string `json:"team"`
+		}
+		if err := json.Unmarshal(request.Config, &config); err != nil {
+			return err
+		}
+		if config.Team != "payments" {
+			return errors.New("image is not signed by team " + config.Team)
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name        string
+		custom      string
+		opts        []Option
+		wantOutcome VerificationOutcome
+		wantReason  ErrorReason
+		wantErr     string
+		wantConfig  string
+		wantImage   string
+	}{{
+		name:        "pass",
+		custom:      `[{"type":"signing-service","config":{"team":"{{ team }}"}}]`,
+		opts:        []Option{WithAttestors(map[string]Attestor{"signing-service": signingService})},
+		wantOutcome: PASS,
+		wantConfig:  `{"team":"payments"}`,
+		wantImage:   "ghcr.io/org/app:v1",
+	}, {
+		name:        "fail",
+		custom:      `[{"type":"signing-service","config":{"team":"platform"}}]`,
+		opts:        []Option{WithAttestors(map[string]Attestor{"signing-service": signingService})},
+		wantOutcome: FAIL,
+		wantErr:     "image is not signed by team platform",
+		wantConfig:  `{"team":"platform"}`,
+		wantImage:   "ghcr.io/org/app:v1",
+	}, {
+		name:        "mirror",
+		custom:      `[{"type":"signing-service","config":{"team":"payments"}}]`,
+		opts:        []Option{WithAttestors(map[string]Attestor{"signing-service": signingService}), WithMirrors(registry.Mirrors{{Prefix: "ghcr.io/*", Mirror: "mirror.corp/ghcr/*"}})},
+		wantOutcome: PASS,
+		wantConfig:  `{"team":"payments"}`,
+		wantImage:   "mirror.corp/ghcr/org/app:v1",
+	}, {
+		name:        "unknown type",
+		custom:      `[{"type":"signing-service"}]`,
+		opts:        []Option{WithCache(cache.NewLRU(10, 0))},
+		wantOutcome: ERROR,
+		wantReason:  ReasonUnknownAttestor,
+		wantErr:     `unknown attestor type "signing-service"`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+			var pol v1alpha1.ImageVerificationPolicy
+			err := json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"custom","match":{"any":[{"family":"sample"}]},"context":[{"name":"team","variable":{"value":"payments"}}],"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/org/*"],"custom":`+tt.custom+`}]}]}}`), &pol)
+			assert.NoError(t, err)
+
+			e := NewEngineFromDClient(nil, tt.opts...)
+			request := Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource}
+			result := e.Apply(context.Background(), request).PolicyResponses[0].RuleResponses[0].VerificationResults[0]
+			assert.Equal(t, tt.wantOutcome, result.VerificationOutcome)
+			assert.Equal(t, tt.wantReason, result.Reason)
+			if tt.wantReason != "" {
+				// errors are not verdicts, they are never cached
+				again := e.Apply(context.Background(), request).PolicyResponses[0].RuleResponses[0].VerificationResults[0]
+				assert.Equal(t, CacheMiss, again.Cache)
+				assert.Equal(t, tt.wantReason, again.Reason)
+			}
+			failures := result.VerificationResponses[0].Failures
+			if tt.wantErr == "" {
+				assert.Empty(t, failures)
+			} else {
+				assert.Len(t, failures, 1)
+				assert.ErrorContains(t, failures[0], tt.wantErr)
+			}
+			if tt.wantConfig == "" {
+				assert.Empty(t, requests)
+			} else {
+				assert.Len(t, requests, 1)
+				assert.JSONEq(t, tt.wantConfig, string(requests[0].Config))
+				assert.Equal(t, tt.wantImage, requests[0].Image)
+				assert.NotNil(t, requests[0].Registry)
+			}
+		})
+	}
+}
+
+func Test_RegisterAttestor(t *testing.T) {
+	pass := AttestorFunc(func(context.Context, AttestorRequest) error { return nil })
+	fail := AttestorFunc(func(context.Context, AttestorRequest) error { return errors.New("registered attestor") })
+	RegisterAttestor("test-register-attestor", fail)
+	assert.Panics(t, func() { RegisterAttestor("test-register-attestor", pass) })
+	assert.Panics(t, func() { RegisterAttestor("", pass) })
+
+	verifier := &imageVerifier{}
+	attestor, ok := verifier.attestor("test-register-attestor")
+	assert.True(t, ok)
+	assert.EqualError(t, attestor.Verify(context.Background(), AttestorRequest{}), "registered attestor")
+
+	verifier.attestors = map[string]Attestor{"test-register-attestor": pass}
+	attestor, ok = verifier.attestor("test-register-attestor")
+	assert.True(t, ok)
+	assert.NoError(t, attestor.Verify(context.Background(), AttestorRequest{}))
+
+	_, ok = verifier.attestor("test-unknown-attestor")
+	assert.False(t, ok)
+}
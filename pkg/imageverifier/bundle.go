@@ -0,0 +1,193 @@
+package imageverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kyverno/kyverno/pkg/images"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/sigstore"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+)
+
+// bundleVerification verifies the image with the Sigstore bundles attached to it as OCI referrers.
+// The bundles are verified offline with the trusted root of the policy, the image passes when one
+// of them verifies or, for attestations, when the statements of the verified bundles pass the
+// attestation conditions. The bundles that cannot be fetched or parsed are failures of their own,
+// the image is not a definitive verdict when one of them could not be fetched.
+func (i *imageVerifier) bundleVerification(ctx context.Context, pol *v1alpha1.Cosign, image string) error {
+	root, err := loadTrustedRoot(pol.TrustedRoot)
+	if err != nil {
+		return err
+	}
+	policy, err := bundlePolicy(pol)
+	if err != nil {
+		return err
+	}
+	digest, err := resolveDigest(ctx, i.registry, image)
+	if err != nil {
+		return fmt.Errorf("failed to resolve image digest: %w", err)
+	}
+	policy.Digest = digest
+	bundles, failures, fetchFailed, err := i.fetchBundles(ctx, image, pol.Repository, digest)
+	if err != nil {
+		return err
+	}
+	fail := verificationFailed
+	if fetchFailed {
+		fail = fmt.Errorf
+	}
+	if len(bundles) == 0 && len(failures) == 0 {
+		return verificationFailed("no Sigstore bundle found for %s", image)
+	}
+
+	var verified int
+	var statements []map[string]interface{}
+	for _, b := range bundles {
+		result, err := root.Verify(ctx, b, policy)
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+		verified++
+		if result.Statement != nil {
+			// the statements are filtered by predicate type as the ones fetched by cosign
+			result.Statement["type"] = result.Statement["predicateType"]
+			statements = append(statements, result.Statement)
+		}
+	}
+
+	if len(pol.InToToAttestations) == 0 {
+		if verified == 0 {
+			return fail("failed to verify Sigstore bundles of %s: %w", image, errors.Join(failures...))
+		}
+		return nil
+	}
+
+	for _, att := range pol.InToToAttestations {
+		if att == nil {
+			continue
+		}
+
+		resp := i.filterStatements(att.Type, &images.Response{Digest: digest, Statements: statements})
+		if len(resp.Statements) == 0 {
+			if len(failures) != 0 {
+				return fail("no verified attestation found for %s and predicate %s: %w", image, att.Type, errors.Join(failures...))
+			}
+			return verificationFailed("no attestation found for %s and predicate %s", image, att.Type)
+		}
+		val, msg, err := i.verifyAttestationConditions(att.Conditions, resp)
+		if err != nil {
+			return fmt.Errorf("failed to check attestations: %w", err)
+		}
+		if !val {
+			return verificationFailed("attestation checks failed for %s and predicate %s: %s", image, att.Type, msg)
+		}
+	}
+	return nil
+}
+
+func loadTrustedRoot(root *v1alpha1.TrustedRoot) (*sigstore.TrustedRoot, error) {
+	switch {
+	case root == nil:
+		return nil, errors.New("a trusted root is required to verify Sigstore bundles")
+	case root.Data != "":
+		return sigstore.ParseTrustedRoot([]byte(root.Data))
+	default:
+		return sigstore.LoadTrustedRoot(root.File)
+	}
+}
+
+func bundlePolicy(pol *v1alpha1.Cosign) (sigstore.Policy, error) {
+	policy := sigstore.Policy{
+		IgnoreTlog: pol.IgnoreTlog,
+		IgnoreSCT:  pol.IgnoreSCT,
+	}
+	if pol.Key != nil {
+		key, err := cryptoutils.UnmarshalPEMToPublicKey([]byte(pol.Key.PublicKey))
+		if err != nil {
+			return policy, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		policy.PublicKey = key
+	} else if pol.Keyless != nil {
+		if pol.Keyless.Issuer == "" || pol.Keyless.Subject == "" {
+			return policy, errors.New("an issuer and a subject are required to verify keyless bundles")
+		}
+		policy.Issuer = pol.Keyless.Issuer
+		policy.Subject = pol.Keyless.Subject
+	}
+	return policy, nil
+}
+
+// fetchBundles returns the Sigstore bundles referring to the image digest, they are looked up in
+// the repository of the image unless another repository is set. The bundles that cannot be fetched
+// or parsed are skipped and returned as failures, fetchFailed is true when one of them could not be
+// fetched.
+func (i *imageVerifier) fetchBundles(ctx context.Context, image, repository, digest string) (bundles []*sigstore.Bundle, failures []error, fetchFailed bool, err error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	repo := ref.Context()
+	if repository != "" {
+		if repo, err = name.NewRepository(repository); err != nil {
+			return nil, nil, false, fmt.Errorf("failed to parse repository %s: %w", repository, err)
+		}
+	}
+	opts, err := i.registry.Options(ctx)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	index, err := gcrremote.Referrers(repo.Digest(digest), opts...)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to fetch referrers of %s: %w", image, err)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to fetch referrers of %s: %w", image, err)
+	}
+
+	for _, desc := range manifest.Manifests {
+		if !sigstore.IsBundleMediaType(desc.ArtifactType) {
+			continue
+		}
+		data, err := fetchBundle(repo.Digest(desc.Digest.String()), opts)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to fetch bundle %s: %w", desc.Digest, err))
+			fetchFailed = true
+			continue
+		}
+		b, err := sigstore.ParseBundle(data)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("invalid bundle %s: %w", desc.Digest, err))
+			continue
+		}
+		bundles = append(bundles, b)
+	}
+	return bundles, failures, fetchFailed, nil
+}
+
+// fetchBundle returns the bundle stored in the first layer of the referrer manifest
+func fetchBundle(ref name.Digest, opts []gcrremote.Option) ([]byte, error) {
+	img, err := gcrremote.Image(ref, opts...)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		return nil, errors.New("manifest has no layer")
+	}
+	rc, err := layers[0].Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
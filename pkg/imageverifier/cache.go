@@ -0,0 +1,201 @@
+package imageverifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	enginecontext "github.com/kyverno/kyverno/pkg/engine/context"
+	"github.com/kyverno/kyverno/pkg/registryclient"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/cache"
+	"github.com/notaryproject/notation-go"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+// ErrVerificationFailed is matched by the failures that are a definitive verdict on the image,
+// e.g. a signature or an attestation that does not match the policy, as opposed to the failures
+// to fetch or resolve what the verification needs. Only verdicts are cached, custom attestors wrap
+// ErrVerificationFailed in the failures that can be cached.
+var ErrVerificationFailed = errors.New("image verification failed")
+
+// verdictError is a failure matching ErrVerificationFailed, its message is left as is
+type verdictError struct {
+	error
+}
+
+func (e verdictError) Is(target error) bool {
+	return target == ErrVerificationFailed
+}
+
+func (e verdictError) Unwrap() error {
+	return e.error
+}
+
+// verificationFailed formats a failure that is a definitive verdict on the image
+func verificationFailed(format string, args ...interface{}) error {
+	return verdictError{fmt.Errorf(format, args...)}
+}
+
+// isVerdict returns true when the failure is a definitive verdict on the image, either reported by
+// the engine or by cosign and notation as a verification failure
+func isVerdict(err error) bool {
+	var (
+		cosignFailure          *cosign.VerificationFailure
+		cosignError            *cosign.VerificationError
+		noMatchingSignatures   *cosign.ErrNoMatchingSignatures
+		noMatchingAttestations *cosign.ErrNoMatchingAttestations
+		noSignaturesFound      *cosign.ErrNoSignaturesFound
+		noCertificateFound     *cosign.ErrNoCertificateFoundOnSignature
+		notationFailure        notation.ErrorVerificationFailed
+		notationMetadata       notation.ErrorUserMetadataVerificationFailed
+	)
+	return errors.Is(err, ErrVerificationFailed) ||
+		errors.As(err, &cosignFailure) ||
+		errors.As(err, &cosignError) ||
+		errors.As(err, &noMatchingSignatures) ||
+		errors.As(err, &noMatchingAttestations) ||
+		errors.As(err, &noSignaturesFound) ||
+		errors.As(err, &noCertificateFound) ||
+		errors.As(err, &notationFailure) ||
+		errors.As(err, &notationMetadata)
+}
+
+// CacheStatus reports whether the result of an image verification was served from the cache
+type CacheStatus string
+
+const (
+	// CacheHit means every verification rule matching the image was served from the cache
+	CacheHit CacheStatus = "HIT"
+	// CacheMiss means at least one verification rule matching the image was verified
+	CacheMiss CacheStatus = "MISS"
+)
+
+// resolveDigest returns the digest of the image, the registry is only queried when the reference has no digest
+func resolveDigest(ctx context.Context, client registryclient.Client, image string) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if digest, ok := ref.(name.Digest); ok {
+		return digest.DigestStr(), nil
+	}
+	desc, err := client.FetchImageDescriptor(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+// hasAttestationConditions returns true when the attestations of the verification rule are checked
+// with conditions, they are substituted with the json context of the resource when evaluated
+func hasAttestationConditions(policy v1alpha1.VerificationRule) bool {
+	for _, c := range policy.Cosign {
+		if c == nil {
+			continue
+		}
+		for _, att := range c.InToToAttestations {
+			if att != nil && len(att.Conditions) != 0 {
+				return true
+			}
+		}
+	}
+	for _, n := range policy.Notary {
+		if n == nil {
+			continue
+		}
+		for _, att := range n.Attestations {
+			if att != nil && len(att.Conditions) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cacheKey identifies the verification of an image digest by the attestors of a verification rule,
+// the trusted roots read from files are part of the key so that a rotated trusted root is not served
+// stale verdicts. The json context is part of the key of the rules checking attestations with
+// conditions, as they may evaluate differently for another resource.
+func cacheKey(digest string, policy v1alpha1.VerificationRule, jsonCtx enginecontext.Interface) (string, error) {
+	var trustedRoots []string
+	for _, c := range policy.Cosign {
+		if c == nil || c.TrustedRoot == nil || c.TrustedRoot.File == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Clean(c.TrustedRoot.File))
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		trustedRoots = append(trustedRoots, hex.EncodeToString(sum[:]))
+	}
+	var conditionsContext interface{}
+	if hasAttestationConditions(policy) {
+		if jsonCtx == nil {
+			return "", errors.New("attestation conditions require a json context")
+		}
+		data, err := jsonCtx.Query("@")
+		if err != nil {
+			return "", err
+		}
+		conditionsContext = data
+	}
+	return cache.Key(digest, struct {
+		Cosign       []*v1alpha1.Cosign         `json:"cosign,omitempty"`
+		Notary       []*v1alpha1.Notary         `json:"notary,omitempty"`
+		Custom       []*v1alpha1.CustomAttestor `json:"custom,omitempty"`
+		TrustedRoots []string                   `json:"trustedRoots,omitempty"`
+		Context      interface{}                `json:"context,omitempty"`
+	}{
+		Cosign:       policy.Cosign,
+		Notary:       policy.Notary,
+		Custom:       policy.Custom,
+		TrustedRoots: trustedRoots,
+		Context:      conditionsContext,
+	})
+}
+
+// verifyRuleWithCache returns the cached response of the verification rule for the image digest,
+// or verifies the image and caches its response. Only definitive verdicts are cached, a response
+// with a failure to fetch or resolve what the verification needs, a timeout or a cancellation is not.
+// The rules calling external services are never cached, their calls and conditions depend on the
+// resource and the context rather than on the image only. The rules checking attestations with
+// conditions are cached by resource context.
+func (i *imageVerifier) verifyRuleWithCache(ctx context.Context, policy v1alpha1.VerificationRule, image, digest string) VerificationResponse {
+	if i.cache == nil || digest == "" || len(policy.ExternalService) != 0 {
+		return i.verifyRule(ctx, policy, image)
+	}
+	key, err := cacheKey(digest, policy, i.jsonCtx)
+	if err != nil {
+		return i.verifyRule(ctx, policy, image)
+	}
+
+	if entry, ok := i.cache.Get(key); ok {
+		resp := VerificationResponse{
+			VerificationRule: policy,
+			Failures:         make([]error, 0, len(entry.Failures)),
+			Cached:           true,
+		}
+		for _, f := range entry.Failures {
+			resp.Failures = append(resp.Failures, errors.New(f))
+		}
+		return resp
+	}
+
+	resp := i.verifyRule(ctx, policy, image)
+	failures := make([]string, 0, len(resp.Failures))
+	for _, err := range resp.Failures {
+		if !isVerdict(err) {
+			return resp
+		}
+		failures = append(failures, err.Error())
+	}
+	i.cache.Set(key, failures)
+	return resp
+}
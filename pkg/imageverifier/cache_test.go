@@ -0,0 +1,147 @@
+package imageverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/cache"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Apply_Cache(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"verified":true}`))
+	}))
+	defer srv.Close()
+
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"ghcr.io/example/app@sha256:74a98f0e4d750c9052f092a7f7a72de7b20f94f176a490088f7a744c76c53ea5"}]}`), &resource)
+	assert.NoError(t, err)
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"external","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/*"],"externalService":[{"apiCall":{"method":"POST","service":{"url":"`+srv.URL+`"}},"conditions":[{"all":[{"key":"{{ verified }}","operator":"Equals","value":true}]}]}]}]}]}}`), &pol)
+	assert.NoError(t, err)
+	request := Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource}
+
+	// the external service calls depend on the resource, their responses are never cached
+	e := NewEngineFromDClient(nil, WithCache(cache.NewLRU(10, 0)))
+	for range 2 {
+		resp := e.Apply(context.Background(), request)
+		result := resp.PolicyResponses[0].RuleResponses[0].VerificationResults[0]
+		assert.Equal(t, PASS, result.VerificationOutcome)
+		assert.Equal(t, CacheMiss, result.Cache)
+		assert.Equal(t, "sha256:74a98f0e4d750c9052f092a7f7a72de7b20f94f176a490088f7a744c76c53ea5", result.Digest)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func Test_CacheKey_TrustedRootFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trusted_root.json")
+	policy := v1alpha1.VerificationRule{Cosign: []*v1alpha1.Cosign{{Bundle: true, TrustedRoot: &v1alpha1.TrustedRoot{File: file}}}}
+	digest := "sha256:74a98f0e4d750c9052f092a7f7a72de7b20f94f176a490088f7a744c76c53ea5"
+
+	_, err := cacheKey(digest, policy, nil)
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(file, []byte(`{"tlogs":[]}`), 0o600))
+	key, err := cacheKey(digest, policy, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(file, []byte(`{"tlogs":[{}]}`), 0o600))
+	rotated, err := cacheKey(digest, policy, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, rotated)
+}
+
+func Test_Apply_CacheVerdicts(t *testing.T) {
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"ghcr.io/example/app@sha256:74a98f0e4d750c9052f092a7f7a72de7b20f94f176a490088f7a744c76c53ea5"}]}`), &resource)
+	assert.NoError(t, err)
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"custom","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/*"],"custom":[{"type":"signing-service"}]}]}]}}`), &pol)
+	assert.NoError(t, err)
+	request := Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCache []CacheStatus
+		wantCalls int32
+	}{{
+		name:      "pass",
+		wantCache: []CacheStatus{CacheMiss, CacheHit},
+		wantCalls: 1,
+	}, {
+		name:      "verdict",
+		err:       fmt.Errorf("%w: signature does not match", ErrVerificationFailed),
+		wantCache: []CacheStatus{CacheMiss, CacheHit},
+		wantCalls: 1,
+	}, {
+		name:      "fetch failure",
+		err:       errors.New("dial tcp: lookup signing.corp: no such host"),
+		wantCache: []CacheStatus{CacheMiss, CacheMiss},
+		wantCalls: 2,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			attestor := AttestorFunc(func(context.Context, AttestorRequest) error {
+				calls.Add(1)
+				return tt.err
+			})
+			e := NewEngineFromDClient(nil, WithCache(cache.NewLRU(10, 0)), WithAttestors(map[string]Attestor{"signing-service": attestor}))
+			for _, want := range tt.wantCache {
+				resp := e.Apply(context.Background(), request)
+				result := resp.PolicyResponses[0].RuleResponses[0].VerificationResults[0]
+				assert.Equal(t, want, result.Cache)
+				if tt.err == nil {
+					assert.Equal(t, PASS, result.VerificationOutcome)
+				} else {
+					assert.Equal(t, FAIL, result.VerificationOutcome)
+					assert.ErrorContains(t, result.VerificationResponses[0].Failures[0], tt.err.Error())
+				}
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func Test_Apply_CacheAttestationConditions(t *testing.T) {
+	dir, publicKey := writeBundleLayout(t, `{"builder":{"id":"https://github.com/org/app"}}`)
+	l, err := registry.OpenLayout(dir)
+	assert.NoError(t, err)
+	trustedRoot := `{"mediaType":"application/vnd.dev.sigstore.trustedroot+json;version=0.1"}`
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"provenance","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/org/*"],"cosign":[{"bundle":true,"key":{"publicKey":%q},"trustedRoot":{"data":%q},"ignoreTlog":true,"intotoAttestations":[{"type":"https://slsa.dev/provenance/v1","conditions":[{"all":[{"key":"{{ builder.id }}","operator":"Equals","value":"{{ resource.builder }}"}]}]}]}]}]}]}}`, publicKey, trustedRoot)), &pol)
+	assert.NoError(t, err)
+
+	// the same image is verified for resources whose attestation conditions evaluate differently
+	e := NewEngineFromDClient(nil, WithBackend(l), WithCache(cache.NewLRU(10, 0)))
+	for _, tt := range []struct {
+		builder     string
+		wantOutcome VerificationOutcome
+		wantCache   CacheStatus
+	}{
+		{builder: "https://github.com/org/app", wantOutcome: PASS, wantCache: CacheMiss},
+		{builder: "https://github.com/other/app", wantOutcome: FAIL, wantCache: CacheMiss},
+		{builder: "https://github.com/org/app", wantOutcome: PASS, wantCache: CacheHit},
+		{builder: "https://github.com/other/app", wantOutcome: FAIL, wantCache: CacheHit},
+	} {
+		var resource interface{}
+		err := json.Unmarshal([]byte(fmt.Sprintf(`{"family":"sample","builder":%q,"containerDefinitions":[{"image":"ghcr.io/org/app:v1"}]}`, tt.builder)), &resource)
+		assert.NoError(t, err)
+		resp := e.Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+		result := resp.PolicyResponses[0].RuleResponses[0].VerificationResults[0]
+		assert.Equal(t, tt.wantOutcome, result.VerificationOutcome, tt.builder)
+		assert.Equal(t, tt.wantCache, result.Cache, tt.builder)
+	}
+}
@@ -22,6 +22,24 @@ import (
 
 // substitute variables
 
+// cloneJsonContext returns an isolated copy of the json context that can be used by a
+// goroutine without affecting the original context and its checkpoints
+func cloneJsonContext(ctx enginectx.Interface, jp jmespath.Interface) (enginectx.Interface, error) {
+	raw, err := ctx.Query("@")
+	if err != nil {
+		return nil, err
+	}
+	data, err := jsonutils.DocumentToUntyped(raw)
+	if err != nil {
+		return nil, err
+	}
+	dataMap, ok := data.(map[string]interface{})
+	if !ok {
+		dataMap = map[string]interface{}{}
+	}
+	return enginectx.NewContextFromRaw(jp, dataMap), nil
+}
+
 func addResourceToJsonContext(ctx enginectx.Interface, resource interface{}) error {
 	data, err := jsonutils.DocumentToUntyped(resource)
 	if err != nil {
@@ -36,7 +54,7 @@ func addResourceToJsonContext(ctx enginectx.Interface, resource interface{}) err
 	return nil
 }
 
-func addContextEntriesToJsonContext(ctx enginectx.Interface, client dclient.Interface, jp jmespath.Interface, entries *[]v1alpha1.ContextEntry) error {
+func addContextEntriesToJsonContext(ctx context.Context, jsonCtx enginectx.Interface, client dclient.Interface, jp jmespath.Interface, entries *[]v1alpha1.ContextEntry) error {
 	if entries == nil {
 		return nil
 	}
@@ -47,7 +65,7 @@ func addContextEntriesToJsonContext(ctx enginectx.Interface, client dclient.Inte
 				Name:     entry.Name,
 				Variable: entry.Variable,
 			}
-			ldr := loaders.NewVariableLoader(logr.Discard(), ctxEntry, ctx, jp)
+			ldr := loaders.NewVariableLoader(logr.Discard(), ctxEntry, jsonCtx, jp)
 			err := ldr.LoadData()
 			if err != nil {
 				return err
@@ -57,7 +75,7 @@ func addContextEntriesToJsonContext(ctx enginectx.Interface, client dclient.Inte
 				Name:    entry.Name,
 				APICall: entry.APICall,
 			}
-			ldr := loaders.NewAPILoader(context.TODO(), logr.Discard(), ctxEntry, ctx, jp, client, apicall.APICallConfiguration{})
+			ldr := loaders.NewAPILoader(ctx, logr.Discard(), ctxEntry, jsonCtx, jp, client, apicall.APICallConfiguration{})
 			err := ldr.LoadData()
 			if err != nil {
 				return err
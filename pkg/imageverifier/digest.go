@@ -0,0 +1,77 @@
+package imageverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/registryclient"
+	imageutils "github.com/kyverno/kyverno/pkg/utils/image"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+)
+
+var (
+	// ErrDigestRequired is reported when an image is not referenced by digest and the verification rule requires it
+	ErrDigestRequired = errors.New("image is not referenced by digest")
+	// ErrDigestMismatch is reported when the tag of an image does not resolve to the digest it is referenced with
+	ErrDigestMismatch = errors.New("image tag does not match digest")
+)
+
+// verifyDigest enforces the digest requirements of the verification rule on the image reference,
+// the tag is resolved from the mirror of the image
+func verifyDigest(ctx context.Context, client registryclient.Client, mirrors registry.Mirrors, policy v1alpha1.VerificationRule, reference string) error {
+	if !policy.RequireDigest && !policy.VerifyDigest {
+		return nil
+	}
+	info, err := imageutils.GetImageInfo(reference, config.NewDefaultConfiguration(false))
+	if err != nil {
+		return err
+	}
+	if policy.RequireDigest && info.Digest == "" {
+		return fmt.Errorf("%w: %s", ErrDigestRequired, reference)
+	}
+	if !policy.VerifyDigest || info.Digest == "" || info.Tag == "" {
+		return nil
+	}
+
+	expected := info.Digest
+	info.Digest = ""
+	tagged := mirrors.Rewrite(info.String())
+	desc, err := client.FetchImageDescriptor(ctx, tagged)
+	if err != nil {
+		return contextError(ctx, fmt.Errorf("failed to resolve digest of %s: %w", tagged, err))
+	}
+	if digest := desc.Digest.String(); digest != expected {
+		return fmt.Errorf("%w: %s resolves to %s", ErrDigestMismatch, reference, digest)
+	}
+	return nil
+}
+
+// verifyRuleWithDigest verifies the image with the verification rule once the image reference
+// satisfies its digest requirements, the attestors are not evaluated otherwise. It holds a worker
+// of the engine while it calls the registries.
+func (i *imageVerifier) verifyRuleWithDigest(ctx context.Context, policy v1alpha1.VerificationRule, image, reference, digest string) VerificationResponse {
+	start := time.Now()
+	release, err := i.acquire(ctx)
+	if err != nil {
+		return VerificationResponse{
+			VerificationRule: policy,
+			Failures:         []error{err},
+			Duration:         time.Since(start),
+		}
+	}
+	defer release()
+	if err := verifyDigest(ctx, i.registry, i.mirrors, policy, reference); err != nil {
+		return VerificationResponse{
+			VerificationRule: policy,
+			Failures:         []error{err},
+			Duration:         time.Since(start),
+		}
+	}
+	resp := i.verifyRuleWithCache(ctx, policy, image, digest)
+	resp.Duration = time.Since(start)
+	return resp
+}
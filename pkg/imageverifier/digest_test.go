@@ -0,0 +1,280 @@
+package imageverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/cache"
+	verifyregistry "github.com/nirmata/json-image-verification/pkg/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Apply_VerifyDigest(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(host + "/test/app:v1")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+	other, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	otherDigest, err := other.Digest()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		image       string
+		verify      string
+		wantOutcome VerificationOutcome
+		wantErr     error
+	}{{
+		name:        "tag without digest",
+		image:       host + "/test/app:v1",
+		verify:      `"requireDigest":true`,
+		wantOutcome: FAIL,
+		wantErr:     ErrDigestRequired,
+	}, {
+		name:        "digest",
+		image:       host + "/test/app@" + digest.String(),
+		verify:      `"requireDigest":true`,
+		wantOutcome: PASS,
+	}, {
+		name:        "tag matching digest",
+		image:       host + "/test/app:v1@" + digest.String(),
+		verify:      `"requireDigest":true,"verifyDigest":true`,
+		wantOutcome: PASS,
+	}, {
+		name:        "tag not matching digest",
+		image:       host + "/test/app:v1@" + otherDigest.String(),
+		verify:      `"requireDigest":true,"verifyDigest":true`,
+		wantOutcome: FAIL,
+		wantErr:     ErrDigestMismatch,
+	}, {
+		name:        "tag not matching digest without verification",
+		image:       host + "/test/app:v1@" + otherDigest.String(),
+		verify:      `"requireDigest":true`,
+		wantOutcome: PASS,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resource interface{}
+			err := json.Unmarshal([]byte(fmt.Sprintf(`{"family":"sample","containerDefinitions":[{"image":"%s"}]}`, tt.image)), &resource)
+			assert.NoError(t, err)
+			var pol v1alpha1.ImageVerificationPolicy
+			err = json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"digest","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["%s/*"],%s}]}]}}`, host, tt.verify)), &pol)
+			assert.NoError(t, err)
+
+			resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+			results := resp.PolicyResponses[0].RuleResponses[0].VerificationResults
+			assert.Len(t, results, 1)
+			assert.Equal(t, tt.wantOutcome, results[0].VerificationOutcome)
+			failures := results[0].VerificationResponses[0].Failures
+			if tt.wantErr == nil {
+				assert.Empty(t, failures)
+			} else {
+				assert.Len(t, failures, 1)
+				assert.True(t, errors.Is(failures[0], tt.wantErr), failures[0])
+			}
+		})
+	}
+}
+
+func Test_Apply_RegistryAuth(t *testing.T) {
+	authenticate := false
+	handler := registry.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); authenticate && (!ok || user != "user" || pass != "pass") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(host + "/test/app:v1")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+	authenticate = true
+
+	var resource interface{}
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"family":"sample","containerDefinitions":[{"image":"%s/test/app:v1@%s"}]}`, host, digest)), &resource)
+	assert.NoError(t, err)
+	policy := func(spec string) *v1alpha1.ImageVerificationPolicy {
+		var pol v1alpha1.ImageVerificationPolicy
+		err := json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{%s"rules":[{"name":"digest","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["%s/*"],"requireDigest":true,"verifyDigest":true}]}]}}`, spec, host)), &pol)
+		assert.NoError(t, err)
+		return &pol
+	}
+	keychain, err := verifyregistry.Auth{Credentials: []verifyregistry.Credential{{Registry: host, Username: "user", Password: "pass"}}}.Keychain()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		policy      *v1alpha1.ImageVerificationPolicy
+		wantOutcome VerificationOutcome
+		wantErr     string
+	}{{
+		name:        "anonymous",
+		policy:      policy(""),
+		wantOutcome: FAIL,
+	}, {
+		name:        "engine credentials",
+		opts:        []Option{WithKeychain(keychain)},
+		policy:      policy(""),
+		wantOutcome: PASS,
+	}, {
+		name:        "image pull secrets without cluster",
+		opts:        []Option{WithKeychain(keychain)},
+		policy:      policy(`"imagePullSecrets":[{"name":"regcred","namespace":"default"}],`),
+		wantOutcome: ERROR,
+		wantErr:     "image pull secrets require a Kubernetes client",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := NewEngineFromDClient(nil, tt.opts...).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{tt.policy}, Resource: resource})
+			rule := resp.PolicyResponses[0].RuleResponses[0]
+			assert.Equal(t, tt.wantOutcome, rule.VerificationOutcome)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, rule.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Apply_RegistryTransport(t *testing.T) {
+	srv := httptest.NewTLSServer(registry.New())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(host + "/test/app:v1")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img, remote.WithTransport(srv.Client().Transport)))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+
+	var resource interface{}
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"family":"sample","containerDefinitions":[{"image":"%s/test/app:v1@%s"}]}`, host, digest)), &resource)
+	assert.NoError(t, err)
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"digest","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["%s/*"],"requireDigest":true,"verifyDigest":true}]}]}}`, host)), &pol)
+	assert.NoError(t, err)
+	request := Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource}
+
+	resp := NewEngineFromDClient(nil).Apply(context.Background(), request)
+	assert.Equal(t, FAIL, resp.PolicyResponses[0].RuleResponses[0].VerificationOutcome)
+
+	resp = NewEngineFromDClient(nil, WithTransport(srv.Client().Transport)).Apply(context.Background(), request)
+	assert.Equal(t, PASS, resp.PolicyResponses[0].RuleResponses[0].VerificationOutcome)
+}
+
+func Test_Apply_Mirrors(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(host + "/dockerhub/test/app:v1")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+
+	var resource interface{}
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"family":"sample","containerDefinitions":[{"image":"test/app:v1@%s"},{"image":"test/app:v1"}]}`, digest)), &resource)
+	assert.NoError(t, err)
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"mirror","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["docker.io/test/*"],"verifyDigest":true,"mutateDigest":true}]}]}}`), &pol)
+	assert.NoError(t, err)
+
+	mirrors := verifyregistry.Mirrors{{Prefix: "docker.io/*", Mirror: host + "/dockerhub/*"}}
+	resp := NewEngineFromDClient(nil, WithMirrors(mirrors)).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+	rule := resp.PolicyResponses[0].RuleResponses[0]
+	assert.Equal(t, PASS, rule.VerificationOutcome)
+	assert.Len(t, rule.VerificationResults, 2)
+	for _, result := range rule.VerificationResults {
+		assert.Equal(t, PASS, result.VerificationOutcome)
+		assert.Equal(t, "docker.io/test/app@"+digest.String(), result.MutatedImage)
+	}
+	assert.Equal(t, "docker.io/test/app@"+digest.String(), rule.VerificationResults[0].Image)
+	assert.Equal(t, host+"/dockerhub/test/app@"+digest.String(), rule.VerificationResults[0].Mirror)
+	assert.Equal(t, "docker.io/test/app:v1", rule.VerificationResults[1].Image)
+	assert.Equal(t, host+"/dockerhub/test/app:v1", rule.VerificationResults[1].Mirror)
+}
+
+func Test_Apply_MaxWorkers(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	handler := registry.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	containers := make([]interface{}, 0, 8)
+	for i := 0; i < 8; i++ {
+		img, err := random.Image(128, 1)
+		assert.NoError(t, err)
+		ref, err := name.ParseReference(fmt.Sprintf("%s/test/app-%d:v1", host, i))
+		assert.NoError(t, err)
+		assert.NoError(t, remote.Write(ref, img))
+		containers = append(containers, map[string]interface{}{"image": ref.String()})
+	}
+	maxInFlight.Store(0)
+	resource := map[string]interface{}{"family": "sample", "containerDefinitions": containers}
+
+	// the digests are resolved for the cache and the attestors fetch the images
+	attestor := AttestorFunc(func(ctx context.Context, request AttestorRequest) error {
+		_, err := request.Registry.FetchImageDescriptor(ctx, request.Image)
+		return err
+	})
+	var pol v1alpha1.ImageVerificationPolicy
+	err := json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"fetch","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["%[1]s/*"],"custom":[{"type":"fetch"}]},{"imageReferences":["%[1]s/*"],"custom":[{"type":"fetch"}]}]}]}}`, host)), &pol)
+	assert.NoError(t, err)
+
+	e := NewEngineFromDClient(nil,
+		WithConcurrency(Concurrency{Images: true, VerificationRules: true, MaxWorkers: 2}),
+		WithCache(cache.NewLRU(100, 0)),
+		WithAttestors(map[string]Attestor{"fetch": attestor}),
+	)
+	resp := e.Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+	assert.Equal(t, PASS, resp.PolicyResponses[0].RuleResponses[0].VerificationOutcome)
+	assert.Positive(t, maxInFlight.Load())
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2))
+}
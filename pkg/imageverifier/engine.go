@@ -2,53 +2,154 @@ package imageverifier
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/kyverno/kyverno/pkg/clients/dclient"
 	"github.com/kyverno/kyverno/pkg/config"
 	enginecontext "github.com/kyverno/kyverno/pkg/engine/context"
 	"github.com/kyverno/kyverno/pkg/engine/jmespath"
+	"github.com/kyverno/kyverno/pkg/registryclient"
 	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/cache"
 	"github.com/nirmata/json-image-verification/pkg/policy"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 type engine struct {
-	client dclient.Interface
+	client      dclient.Interface
+	concurrency Concurrency
+	// workers is a semaphore bounding the number of verifications running at the same time
+	workers chan struct{}
+	cache   cache.Cache
+	// keychain resolves the registry credentials of the engine, registries are accessed anonymously when nil
+	keychain authn.Keychain
+	// transport is the transport used to access the registries, the default transport is used when nil
+	transport http.RoundTripper
+	// backend provides the registry clients, the registries are accessed with the transport when nil
+	backend registry.Backend
+	// registry is the registry client used with the credentials of the engine
+	registry registryclient.Client
+	// mirrors rewrites the image references before they are pulled from the registries
+	mirrors registry.Mirrors
+	// attestors are the custom attestors of the engine by type
+	attestors map[string]Attestor
+}
+
+// Concurrency configures how the engine parallelizes image verification. Every goroutine
+// works on its own copy of the json context, the zero value verifies everything sequentially.
+type Concurrency struct {
+	// Images enables verifying the images extracted by a rule concurrently
+	Images bool
+	// VerificationRules enables evaluating the verification rules of an image concurrently
+	VerificationRules bool
+	// MaxWorkers is the maximum number of digest resolutions and verification rules evaluated at the
+	// same time by the engine, it bounds the concurrent registry calls. It defaults to the number of CPUs.
+	MaxWorkers int
+}
+
+// Option configures the engine
+type Option func(*engine)
+
+// WithConcurrency sets the concurrency model used by the engine
+func WithConcurrency(concurrency Concurrency) Option {
+	return func(e *engine) {
+		e.concurrency = concurrency
+	}
 }
 
 type Request struct {
 	Policies []*v1alpha1.ImageVerificationPolicy
-	Resource interface{}
+	// Exceptions are the policy exceptions waiving the failures of the policies
+	Exceptions []*v1alpha1.PolicyException
+	Resource   interface{}
 }
 
 type Response struct {
 	Resource        interface{}
 	PolicyResponses []PolicyResponse
+	// Patches is a JSON Patch (RFC 6902) against the resource pinning the images verified by
+	// verification rules with digest mutation enabled
+	Patches []PatchOperation
 }
 
 type PolicyResponse struct {
-	Policy        v1alpha1.ImageVerificationPolicy
-	RuleResponses []RuleResponse
+	Policy v1alpha1.ImageVerificationPolicy
+	// ValidationFailureAction is the validation failure action of the policy, it defaults to Enforce
+	ValidationFailureAction v1alpha1.ValidationFailureAction
+	RuleResponses           []RuleResponse
 }
 
 type RuleResponse struct {
-	Rule               v1alpha1.ImageVerificationRule
-	VerificationResult VerificationResult
+	Rule v1alpha1.ImageVerificationRule
+	// ValidationFailureAction is the validation failure action of the rule, inherited from the
+	// policy unless the rule overrides it
+	ValidationFailureAction v1alpha1.ValidationFailureAction
+	// VerificationOutcome is the aggregated outcome of all the images verified by the rule
+	VerificationOutcome VerificationOutcome
+	// Error is only populated when the rule could not be evaluated
+	Error error
+	// Reason is only populated for ERROR verification outcome caused by a timeout, a cancellation or
+	// an unknown attestor type
+	Reason ErrorReason
+	// VerificationResults contains one result per image extracted from the resource
+	VerificationResults []VerificationResult
+	// Duration is the time spent evaluating the rule
+	Duration time.Duration
+}
+
+// Blocking returns true when the rule failed or errored and its failures are enforced, the
+// failures of rules in Audit mode are only reported
+func (r RuleResponse) Blocking() bool {
+	return (r.VerificationOutcome == FAIL || r.VerificationOutcome == ERROR) && r.ValidationFailureAction != v1alpha1.Audit
 }
 
 type VerificationResult struct {
+	// Key is the extractor key of the image, it defaults to the JSON pointer of the image in the resource
+	Key string
+	// Pointer is the JSON pointer of the image in the resource
+	Pointer             string
 	Image               string
 	VerificationOutcome VerificationOutcome
 	// Error is only populated for ERROR verification outcome
-	Error                 error
+	Error error
+	// Reason is only populated for ERROR verification outcome caused by a timeout, a cancellation or
+	// an unknown attestor type
+	Reason ErrorReason
+	// Exception is the name of the policy exception waiving the failures of the image, it is only
+	// populated for EXCEPTED verification outcome
+	Exception string
+	// Digest is the resolved digest of the image, it is only populated when the cache or the digest
+	// mutation is enabled, the image is then verified and pinned by this digest
+	Digest string
+	// Cache is the cache status of the verification, it is empty when the cache is disabled
+	Cache CacheStatus
+	// Mirror is the image reference verified in place of the image, it is only populated when a
+	// registry mirror rewrites the image
+	Mirror string
+	// MutatedImage is the image reference pinned to the verified digest, it is only populated when
+	// a verification rule matching the image enables digest mutation
+	MutatedImage          string
 	VerificationResponses []VerificationResponse
+	// Duration is the time spent verifying the image
+	Duration time.Duration
 }
 
 type VerificationResponse struct {
 	VerificationRule v1alpha1.VerificationRule
 	Failures         []error
+	// Cached is true when the response was served from the cache
+	Cached bool
+	// Duration is the time spent evaluating the verification rule
+	Duration time.Duration
 }
 
 type VerificationOutcome string
@@ -58,117 +159,308 @@ const (
 	SKIP  VerificationOutcome = "SKIP"
 	FAIL  VerificationOutcome = "FAIL"
 	ERROR VerificationOutcome = "ERROR"
+	// EXCEPTED is the outcome of a failure waived by a policy exception
+	EXCEPTED VerificationOutcome = "EXCEPTED"
+)
+
+// ErrorReason describes why a verification ended with an ERROR outcome
+type ErrorReason string
+
+const (
+	ReasonTimeout         ErrorReason = "Timeout"
+	ReasonCanceled        ErrorReason = "Canceled"
+	ReasonUnknownAttestor ErrorReason = "UnknownAttestor"
 )
 
-func NewEngine(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) (*engine, error) {
+// WithKeychain sets the keychain resolving the credentials of the registries accessed by the attestors
+func WithKeychain(keychain authn.Keychain) Option {
+	return func(e *engine) {
+		e.keychain = keychain
+	}
+}
+
+// WithTransport sets the transport used to access the registries, e.g. to trust private certificate
+// authorities, access insecure registries or use a proxy
+func WithTransport(transport http.RoundTripper) Option {
+	return func(e *engine) {
+		e.transport = transport
+	}
+}
+
+// WithBackend sets the backend the images, signatures and attestations are resolved from, e.g. an
+// OCI image layout to verify images without registry access. The transport is ignored.
+func WithBackend(backend registry.Backend) Option {
+	return func(e *engine) {
+		e.backend = backend
+	}
+}
+
+// WithMirrors sets the mirrors the images are pulled from. The attestors verify the images of the
+// mirrors while the image references of the verification rules match the original images.
+func WithMirrors(mirrors registry.Mirrors) Option {
+	return func(e *engine) {
+		e.mirrors = mirrors
+	}
+}
+
+// WithCache enables caching the verification results by image digest and attestor configuration
+func WithCache(c cache.Cache) Option {
+	return func(e *engine) {
+		e.cache = c
+	}
+}
+
+func NewEngine(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, opts ...Option) (*engine, error) {
 	client, err := dclient.NewClient(ctx, dynamicClient, kubeClient, 15*time.Second)
 	if err != nil {
 		return nil, err
 	}
-	return &engine{
-		client: client,
-	}, nil
+	return NewEngineFromDClient(client, opts...), nil
 }
 
-func NewEngineFromDClient(client dclient.Interface) *engine {
-	return &engine{
+func NewEngineFromDClient(client dclient.Interface, opts ...Option) *engine {
+	e := &engine{
 		client: client,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.registry = e.newRegistryClient(e.keychain)
+	if e.concurrency.Images || e.concurrency.VerificationRules {
+		workers := e.concurrency.MaxWorkers
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		e.workers = make(chan struct{}, workers)
+	}
+	return e
 }
 
-func (e *engine) Apply(request Request) Response {
+func (e *engine) Apply(ctx context.Context, request Request) Response {
 	response := Response{
 		Resource:        request.Resource,
 		PolicyResponses: make([]PolicyResponse, len(request.Policies)),
 	}
 	jp := jmespath.New(config.NewDefaultConfiguration(false))
 	jsonContext := enginecontext.NewContext(jp)
+	now := metav1.Now()
 	for i, pol := range request.Policies {
 		policyResponse := PolicyResponse{
-			Policy:        *pol,
-			RuleResponses: make([]RuleResponse, len(pol.Spec.Rules)),
+			Policy:                  *pol,
+			ValidationFailureAction: pol.Spec.GetValidationFailureAction(nil),
+			RuleResponses:           make([]RuleResponse, len(pol.Spec.Rules)),
 		}
+		registryClient, registryErr := e.policyRegistryClient(ctx, pol)
 		for j, r := range pol.Spec.Rules {
-			jsonContext.Checkpoint()
-			defer jsonContext.Restore()
-			ruleResponse := RuleResponse{
-				Rule: r,
+			start := time.Now()
+			if registryErr != nil {
+				policyResponse.RuleResponses[j] = RuleResponse{Rule: r, VerificationOutcome: ERROR, Error: registryErr}
+			} else {
+				policyResponse.RuleResponses[j] = e.applyRule(ctx, jsonContext, jp, registryClient, r, request.Resource)
 			}
-			err := addResourceToJsonContext(jsonContext, request.Resource)
-			if err != nil {
-				ruleResponse.VerificationResult = VerificationResult{
-					VerificationOutcome: ERROR,
-					Error:               err,
-				}
-				policyResponse.RuleResponses[j] = ruleResponse
-				continue
+			if err := applyExceptions(ctx, &policyResponse.RuleResponses[j], pol.Name, request.Exceptions, request.Resource, now); err != nil {
+				policyResponse.RuleResponses[j].VerificationOutcome = ERROR
+				policyResponse.RuleResponses[j].Error = fmt.Errorf("failed to match policy exceptions: %w", err)
 			}
+			policyResponse.RuleResponses[j].ValidationFailureAction = pol.Spec.GetValidationFailureAction(&pol.Spec.Rules[j])
+			policyResponse.RuleResponses[j].Duration = time.Since(start)
+		}
+		response.PolicyResponses[i] = policyResponse
+	}
+	response.Patches = digestPatches(response.PolicyResponses)
+	return response
+}
 
-			errs, err := policy.Match(context.Background(), r.Match, request.Resource)
-			if err != nil {
-				ruleResponse.VerificationResult = VerificationResult{
-					VerificationOutcome: ERROR,
-					Error:               err,
-				}
-				policyResponse.RuleResponses[j] = ruleResponse
-				continue
-			}
-			if len(errs) > 0 {
-				ruleResponse.VerificationResult = VerificationResult{
-					VerificationOutcome: SKIP,
-				}
-				policyResponse.RuleResponses[j] = ruleResponse
-				continue
-			}
+// newRegistryClient returns a registry client of the engine backend resolving the credentials with the keychain
+func (e *engine) newRegistryClient(keychain authn.Keychain) registryclient.Client {
+	backend := e.backend
+	if backend == nil {
+		backend = registry.Remote{Transport: e.transport}
+	}
+	return backend.Client(keychain)
+}
 
-			images, err := policy.GetImages(request.Resource, r.ImageExtractor)
-			if err != nil {
-				ruleResponse.VerificationResult = VerificationResult{
-					VerificationOutcome: ERROR,
-					Error:               err,
-				}
-				policyResponse.RuleResponses[j] = ruleResponse
-				continue
-			}
+// policyRegistryClient returns the registry client used to verify the images of the policy, the
+// image pull secrets of the policy take precedence over the credentials of the engine
+func (e *engine) policyRegistryClient(ctx context.Context, pol *v1alpha1.ImageVerificationPolicy) (registryclient.Client, error) {
+	if len(pol.Spec.ImagePullSecrets) == 0 {
+		return e.registry, nil
+	}
+	var kubeClient kubernetes.Interface
+	if e.client != nil {
+		kubeClient = e.client.GetKubeClient()
+	}
+	secrets, err := registry.PullSecretsKeychain(ctx, kubeClient, pol.Spec.ImagePullSecrets)
+	if err != nil {
+		return nil, err
+	}
+	if e.keychain == nil {
+		return e.newRegistryClient(secrets), nil
+	}
+	return e.newRegistryClient(authn.NewMultiKeychain(secrets, e.keychain)), nil
+}
 
-			err = addImagesToJsonContext(jsonContext, images)
-			if err != nil {
-				ruleResponse.VerificationResult = VerificationResult{
-					VerificationOutcome: ERROR,
-					Error:               err,
-				}
-				policyResponse.RuleResponses[j] = ruleResponse
-				continue
-			}
+func (e *engine) applyRule(ctx context.Context, jsonContext enginecontext.Interface, jp jmespath.Interface, registryClient registryclient.Client, r v1alpha1.ImageVerificationRule, resource interface{}) RuleResponse {
+	jsonContext.Checkpoint()
+	defer jsonContext.Restore()
+	ruleResponse := RuleResponse{
+		Rule: r,
+	}
+	ruleError := func(err error) RuleResponse {
+		ruleResponse.VerificationOutcome = ERROR
+		ruleResponse.Error = contextError(ctx, err)
+		ruleResponse.Reason = errorReason(ruleResponse.Error)
+		return ruleResponse
+	}
 
-			err = addContextEntriesToJsonContext(jsonContext, e.client, jp, r.Context)
-			if err != nil {
-				ruleResponse.VerificationResult = VerificationResult{
-					VerificationOutcome: ERROR,
-					Error:               err,
-				}
-				policyResponse.RuleResponses[j] = ruleResponse
-				continue
-			}
+	if r.Timeout != nil && r.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout.Duration)
+		defer cancel()
+	}
+	if ctx.Err() != nil {
+		return ruleError(nil)
+	}
+
+	err := addResourceToJsonContext(jsonContext, resource)
+	if err != nil {
+		return ruleError(err)
+	}
+
+	errs, err := policy.Match(ctx, r.Match, resource)
+	if err != nil {
+		return ruleError(err)
+	}
+	if len(errs) > 0 {
+		ruleResponse.VerificationOutcome = SKIP
+		return ruleResponse
+	}
+	// an exclude without any nor all assertion trees excludes nothing
+	if r.Exclude != nil && (len(r.Exclude.Any) != 0 || len(r.Exclude.All) != 0) {
+		errs, err := policy.Match(ctx, *r.Exclude, resource)
+		if err != nil {
+			return ruleError(err)
+		}
+		if len(errs) == 0 {
+			ruleResponse.VerificationOutcome = SKIP
+			return ruleResponse
+		}
+	}
+
+	refs, err := policy.ExtractImages(resource, r.ImageExtractor)
+	if err != nil {
+		return ruleError(err)
+	}
+	images := make(map[string]string, len(refs))
+	for k, ref := range refs {
+		images[k] = ref.Image
+	}
 
-			rule, err := substituteVariablesInRule(r.DeepCopy(), jsonContext)
-			if err != nil {
-				ruleResponse.VerificationResult = VerificationResult{
-					VerificationOutcome: ERROR,
-					Error:               err,
+	err = addImagesToJsonContext(jsonContext, images)
+	if err != nil {
+		return ruleError(err)
+	}
+
+	err = addContextEntriesToJsonContext(ctx, jsonContext, e.client, jp, r.Context)
+	if err != nil {
+		return ruleError(err)
+	}
+
+	rule, err := substituteVariablesInRule(r.DeepCopy(), jsonContext)
+	if err != nil {
+		return ruleError(err)
+	}
+
+	// images are verified in a stable order so that responses are reproducible
+	keys := make([]string, 0, len(images))
+	for k := range images {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	verifier := NewVerifier(rule.Rules, e.client, jsonContext, jp, rule.RequiredCount)
+	verifier.parallel = e.concurrency.VerificationRules
+	verifier.workers = e.workers
+	verifier.cache = e.cache
+	verifier.registry = registryClient
+	verifier.mirrors = e.mirrors
+	verifier.attestors = e.attestors
+	ruleResponse.VerificationResults = make([]VerificationResult, len(keys))
+	if !e.concurrency.Images {
+		for idx, k := range keys {
+			ruleResponse.VerificationResults[idx] = verifyImage(ctx, verifier, k, refs[k])
+		}
+	} else {
+		var wg sync.WaitGroup
+		for idx, k := range keys {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := verifier.isolated()
+				if err != nil {
+					ruleResponse.VerificationResults[idx] = VerificationResult{
+						Key:                 k,
+						Pointer:             refs[k].Pointer,
+						Image:               images[k],
+						VerificationOutcome: ERROR,
+						Error:               fmt.Errorf("failed to copy json context: %w", err),
+					}
+					return
 				}
-				policyResponse.RuleResponses[j] = ruleResponse
-				continue
-			}
+				ruleResponse.VerificationResults[idx] = verifyImage(ctx, v, k, refs[k])
+			}()
+		}
+		wg.Wait()
+	}
+	ruleResponse.VerificationOutcome = aggregateOutcome(ruleResponse.VerificationResults)
+	for _, result := range ruleResponse.VerificationResults {
+		if result.Reason != "" {
+			ruleResponse.Reason = result.Reason
+			break
+		}
+	}
+	return ruleResponse
+}
+
+func verifyImage(ctx context.Context, verifier *imageVerifier, key string, ref policy.ImageReference) VerificationResult {
+	start := time.Now()
+	result := verifier.verify(ctx, ref.Image, ref.Reference)
+	result.Key = key
+	result.Pointer = ref.Pointer
+	if result.VerificationOutcome == PASS && ref.Mutable && verifier.mutateDigest(ref.Image) {
+		// the image is pinned to the digest it was verified with, it is not resolved again
+		mutated, err := pinDigest(ref.Image, result.Digest)
+		if err != nil {
+			result.VerificationOutcome = ERROR
+			result.Error = fmt.Errorf("failed to pin digest of %s: %w", ref.Image, err)
+		} else {
+			result.MutatedImage = mutated
+		}
+	}
+	result.Duration = time.Since(start)
+	return result
+}
 
-			verifier := NewVerifier(rule.Rules, e.client, jsonContext, jp, rule.RequiredCount)
-			for _, v := range images {
-				result := verifier.Verify(v)
-				ruleResponse.VerificationResult = result
+// aggregateOutcome computes the rule outcome from the outcome of every image, any ERROR or FAIL
+// fails the rule, the rule is EXCEPTED when failures were only waived by exceptions, and the rule
+// is only skipped when no image was verified.
+func aggregateOutcome(results []VerificationResult) VerificationOutcome {
+	outcome := SKIP
+	for _, r := range results {
+		switch r.VerificationOutcome {
+		case ERROR:
+			return ERROR
+		case FAIL:
+			outcome = FAIL
+		case EXCEPTED:
+			if outcome != FAIL {
+				outcome = EXCEPTED
+			}
+		case PASS:
+			if outcome == SKIP {
+				outcome = PASS
 			}
-			policyResponse.RuleResponses[j] = ruleResponse
 		}
-		response.PolicyResponses[i] = policyResponse
 	}
-	return response
+	return outcome
 }
@@ -0,0 +1,433 @@
+package imageverifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Apply_PerImageResults(t *testing.T) {
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"docker.io/nginx:1.25"},{"image":"docker.io/busybox:1.36"}]}`), &resource)
+	assert.NoError(t, err)
+
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"skip-all","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/*"]}]}]}}`), &pol)
+	assert.NoError(t, err)
+
+	resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+	assert.Len(t, resp.PolicyResponses, 1)
+	ruleResp := resp.PolicyResponses[0].RuleResponses[0]
+	assert.NoError(t, ruleResp.Error)
+	assert.Equal(t, SKIP, ruleResp.VerificationOutcome)
+	assert.Len(t, ruleResp.VerificationResults, 2)
+	assert.Equal(t, "/containerDefinitions/0/image", ruleResp.VerificationResults[0].Key)
+	assert.Equal(t, "docker.io/nginx:1.25", ruleResp.VerificationResults[0].Image)
+	assert.Equal(t, "/containerDefinitions/1/image", ruleResp.VerificationResults[1].Key)
+	assert.Equal(t, "docker.io/busybox:1.36", ruleResp.VerificationResults[1].Image)
+}
+
+func Test_Apply_Exclude(t *testing.T) {
+	var pol v1alpha1.ImageVerificationPolicy
+	err := json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"exclude","match":{"any":[{"family":"sample"},{"family":"legacy"}]},"exclude":{"any":[{"family":"legacy"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["*"]}]}]}}`), &pol)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		family string
+		want   VerificationOutcome
+	}{
+		{family: "sample", want: PASS},
+		{family: "legacy", want: SKIP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.family, func(t *testing.T) {
+			resource := map[string]interface{}{"family": tt.family, "containerDefinitions": []interface{}{map[string]interface{}{"image": "docker.io/nginx:1.25"}}}
+			resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+			ruleResp := resp.PolicyResponses[0].RuleResponses[0]
+			assert.NoError(t, ruleResp.Error)
+			assert.Equal(t, tt.want, ruleResp.VerificationOutcome)
+		})
+	}
+
+	t.Run("empty exclude", func(t *testing.T) {
+		var pol v1alpha1.ImageVerificationPolicy
+		err := json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"exclude","match":{"any":[{"family":"sample"}]},"exclude":{},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["*"]}]}]}}`), &pol)
+		assert.NoError(t, err)
+		resource := map[string]interface{}{"family": "sample", "containerDefinitions": []interface{}{map[string]interface{}{"image": "docker.io/nginx:1.25"}}}
+		resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+		ruleResp := resp.PolicyResponses[0].RuleResponses[0]
+		assert.NoError(t, ruleResp.Error)
+		assert.Equal(t, PASS, ruleResp.VerificationOutcome)
+	})
+}
+
+func Test_Apply_SkipImageReferences(t *testing.T) {
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"docker.io/nginx:1.25"},{"image":"docker.io/busybox:1.36"}]}`), &resource)
+	assert.NoError(t, err)
+
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"skip-base","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["docker.io/*"],"skipImageReferences":["docker.io/busybox:*"]}]}]}}`), &pol)
+	assert.NoError(t, err)
+
+	resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+	ruleResp := resp.PolicyResponses[0].RuleResponses[0]
+	assert.NoError(t, ruleResp.Error)
+	assert.Equal(t, PASS, ruleResp.VerificationOutcome)
+	assert.Equal(t, "docker.io/nginx:1.25", ruleResp.VerificationResults[0].Image)
+	assert.Equal(t, PASS, ruleResp.VerificationResults[0].VerificationOutcome)
+	assert.Equal(t, "docker.io/busybox:1.36", ruleResp.VerificationResults[1].Image)
+	assert.Equal(t, SKIP, ruleResp.VerificationResults[1].VerificationOutcome)
+}
+
+func Test_Apply_Exceptions(t *testing.T) {
+	var pol v1alpha1.ImageVerificationPolicy
+	err := json.Unmarshal([]byte(`{"metadata":{"name":"digests"},"spec":{"rules":[{"name":"require-digest","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["*"],"requireDigest":true}]}]}}`), &pol)
+	assert.NoError(t, err)
+	resource := map[string]interface{}{"family": "sample", "containerDefinitions": []interface{}{
+		map[string]interface{}{"image": "docker.io/nginx:1.25"},
+		map[string]interface{}{"image": "docker.io/busybox:1.36"},
+	}}
+	exception := func(spec string) *v1alpha1.PolicyException {
+		var e v1alpha1.PolicyException
+		assert.NoError(t, json.Unmarshal([]byte(`{"metadata":{"name":"legacy"},"spec":`+spec+`}`), &e))
+		return &e
+	}
+
+	tests := []struct {
+		name          string
+		exception     *v1alpha1.PolicyException
+		want          VerificationOutcome
+		wantExcepted  []bool
+		wantException string
+	}{{
+		name:         "no exception",
+		want:         FAIL,
+		wantExcepted: []bool{false, false},
+	}, {
+		name:          "all images",
+		exception:     exception(`{"exceptions":[{"policyName":"digests"}],"expires":"2999-01-01T00:00:00Z"}`),
+		want:          EXCEPTED,
+		wantExcepted:  []bool{true, true},
+		wantException: "legacy",
+	}, {
+		name:          "image references",
+		exception:     exception(`{"exceptions":[{"policyName":"digests","ruleNames":["require-digest"]}],"imageReferences":["docker.io/busybox:*"],"expires":"2999-01-01T00:00:00Z"}`),
+		want:          FAIL,
+		wantExcepted:  []bool{false, true},
+		wantException: "legacy",
+	}, {
+		name:         "other rule",
+		exception:    exception(`{"exceptions":[{"policyName":"digests","ruleNames":["signatures"]}],"expires":"2999-01-01T00:00:00Z"}`),
+		want:         FAIL,
+		wantExcepted: []bool{false, false},
+	}, {
+		name:          "matching resource",
+		exception:     exception(`{"exceptions":[{"policyName":"digests"}],"match":{"any":[{"family":"sample"}]},"expires":"2999-01-01T00:00:00Z"}`),
+		want:          EXCEPTED,
+		wantExcepted:  []bool{true, true},
+		wantException: "legacy",
+	}, {
+		name:         "other resource",
+		exception:    exception(`{"exceptions":[{"policyName":"digests"}],"match":{"any":[{"family":"other"}]},"expires":"2999-01-01T00:00:00Z"}`),
+		want:         FAIL,
+		wantExcepted: []bool{false, false},
+	}, {
+		name:         "expired",
+		exception:    exception(`{"exceptions":[{"policyName":"digests"}],"expires":"2020-01-01T00:00:00Z"}`),
+		want:         FAIL,
+		wantExcepted: []bool{false, false},
+	}, {
+		name:         "no expiry",
+		exception:    exception(`{"exceptions":[{"policyName":"digests"}]}`),
+		want:         FAIL,
+		wantExcepted: []bool{false, false},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource}
+			if tt.exception != nil {
+				request.Exceptions = []*v1alpha1.PolicyException{tt.exception}
+			}
+			ruleResp := NewEngineFromDClient(nil).Apply(context.Background(), request).PolicyResponses[0].RuleResponses[0]
+			assert.NoError(t, ruleResp.Error)
+			assert.Equal(t, tt.want, ruleResp.VerificationOutcome)
+			for i, excepted := range tt.wantExcepted {
+				result := ruleResp.VerificationResults[i]
+				if excepted {
+					assert.Equal(t, EXCEPTED, result.VerificationOutcome)
+					assert.Equal(t, tt.wantException, result.Exception)
+				} else {
+					assert.Equal(t, FAIL, result.VerificationOutcome)
+					assert.Empty(t, result.Exception)
+				}
+			}
+		})
+	}
+}
+
+func Test_Apply_ValidationFailureAction(t *testing.T) {
+	var pol v1alpha1.ImageVerificationPolicy
+	err := json.Unmarshal([]byte(`{"spec":{"validationFailureAction":"Audit","rules":[{"name":"audit","imageExtractors":[{"path":"/image/"}],"verify":[{"imageReferences":["*"]}]},{"name":"enforce","validationFailureAction":"Enforce","imageExtractors":[{"path":"/image/"}],"verify":[{"imageReferences":["*"]}]}]}}`), &pol)
+	assert.NoError(t, err)
+
+	resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: map[string]interface{}{"image": "ghcr.io/nirmata/app:v1"}})
+	assert.Equal(t, v1alpha1.Audit, resp.PolicyResponses[0].ValidationFailureAction)
+	assert.Equal(t, v1alpha1.Audit, resp.PolicyResponses[0].RuleResponses[0].ValidationFailureAction)
+	assert.Equal(t, v1alpha1.Enforce, resp.PolicyResponses[0].RuleResponses[1].ValidationFailureAction)
+}
+
+func Test_RuleResponse_Blocking(t *testing.T) {
+	tests := []struct {
+		outcome VerificationOutcome
+		action  v1alpha1.ValidationFailureAction
+		want    bool
+	}{
+		{PASS, v1alpha1.Enforce, false},
+		{SKIP, v1alpha1.Enforce, false},
+		{FAIL, v1alpha1.Enforce, true},
+		{ERROR, v1alpha1.Enforce, true},
+		{FAIL, "", true},
+		{FAIL, v1alpha1.Audit, false},
+		{ERROR, v1alpha1.Audit, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.outcome, tt.action), func(t *testing.T) {
+			assert.Equal(t, tt.want, RuleResponse{VerificationOutcome: tt.outcome, ValidationFailureAction: tt.action}.Blocking())
+		})
+	}
+}
+
+func Test_AggregateOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []VerificationOutcome
+		want     VerificationOutcome
+	}{
+		{name: "no images", want: SKIP},
+		{name: "all skipped", outcomes: []VerificationOutcome{SKIP, SKIP}, want: SKIP},
+		{name: "pass and skip", outcomes: []VerificationOutcome{SKIP, PASS}, want: PASS},
+		{name: "failing sidecar", outcomes: []VerificationOutcome{PASS, FAIL, PASS}, want: FAIL},
+		{name: "error", outcomes: []VerificationOutcome{FAIL, ERROR, PASS}, want: ERROR},
+		{name: "excepted", outcomes: []VerificationOutcome{PASS, EXCEPTED}, want: EXCEPTED},
+		{name: "excepted and fail", outcomes: []VerificationOutcome{EXCEPTED, FAIL}, want: FAIL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make([]VerificationResult, 0, len(tt.outcomes))
+			for _, o := range tt.outcomes {
+				results = append(results, VerificationResult{VerificationOutcome: o})
+			}
+			assert.Equal(t, tt.want, aggregateOutcome(results))
+		})
+	}
+}
+
+func Test_Apply_Concurrency(t *testing.T) {
+	containers := make([]interface{}, 0, 20)
+	for i := 0; i < 20; i++ {
+		containers = append(containers, map[string]interface{}{"image": fmt.Sprintf("ghcr.io/example/app-%02d:v1", i)})
+	}
+	resource := map[string]interface{}{"family": "sample", "containerDefinitions": containers}
+
+	var pol v1alpha1.ImageVerificationPolicy
+	err := json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"no-attestors","count":2,"match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/*"]},{"imageReferences":["*/example/*"]},{"imageReferences":["docker.io/*"]}]}]}}`), &pol)
+	assert.NoError(t, err)
+	request := Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource}
+
+	want := NewEngineFromDClient(nil).Apply(context.Background(), request)
+	got := NewEngineFromDClient(nil, WithConcurrency(Concurrency{Images: true, VerificationRules: true, MaxWorkers: 4})).Apply(context.Background(), request)
+
+	wantResults := want.PolicyResponses[0].RuleResponses[0].VerificationResults
+	gotResults := got.PolicyResponses[0].RuleResponses[0].VerificationResults
+	assert.Len(t, gotResults, 20)
+	assert.Equal(t, PASS, got.PolicyResponses[0].RuleResponses[0].VerificationOutcome)
+	for i := range wantResults {
+		assert.Equal(t, wantResults[i].Key, gotResults[i].Key)
+		assert.Equal(t, wantResults[i].Image, gotResults[i].Image)
+		assert.Equal(t, wantResults[i].VerificationOutcome, gotResults[i].VerificationOutcome)
+	}
+}
+
+// writeSignedLayout writes an OCI layout with the image ghcr.io/org/app:v1, signed with the key
+// when signed is true, and returns the layout path and the public key of the signature
+func writeSignedLayout(t *testing.T, signed bool) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	assert.NoError(t, err)
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{"org.opencontainers.image.ref.name": "ghcr.io/org/app:v1"})))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	if signed {
+		payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"ghcr.io/org/app"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+		hash := sha256.Sum256(payload)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		assert.NoError(t, err)
+		sig, err := mutate.Append(empty.Image, mutate.Addendum{
+			Layer:       static.NewLayer(payload, "application/vnd.dev.cosign.simplesigning.v1+json"),
+			Annotations: map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(signature)},
+		})
+		assert.NoError(t, err)
+		tag := fmt.Sprintf("ghcr.io/org/app:%s-%s.sig", digest.Algorithm, digest.Hex)
+		assert.NoError(t, p.AppendImage(sig, layout.WithAnnotations(map[string]string{"org.opencontainers.image.ref.name": tag})))
+	}
+	return dir, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+}
+
+// writeBundleLayout writes an OCI layout with an image and a Sigstore bundle signing it with a key,
+// attached to the image as an OCI referrer next to an invalid bundle. The bundle signs an in-toto statement of the image with
+// the SLSA provenance predicate when predicate is set, and the image digest otherwise.
+func writeBundleLayout(t *testing.T, predicate string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	assert.NoError(t, err)
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	assert.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{"org.opencontainers.image.ref.name": "ghcr.io/org/app:v1"})))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+	desc, err := partial.Descriptor(img)
+	assert.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	var bundle []byte
+	if predicate != "" {
+		statement := fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"ghcr.io/org/app","digest":{"sha256":%q}}],"predicateType":"https://slsa.dev/provenance/v1","predicate":%s}`, digest.Hex, predicate)
+		payloadType := "application/vnd.in-toto+json"
+		hash := sha256.Sum256([]byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(statement), statement)))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		assert.NoError(t, err)
+		bundle = []byte(fmt.Sprintf(`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","verificationMaterial":{"publicKey":{"hint":"key"}},"dsseEnvelope":{"payload":%q,"payloadType":%q,"signatures":[{"sig":%q}]}}`, base64.StdEncoding.EncodeToString([]byte(statement)), payloadType, base64.StdEncoding.EncodeToString(signature)))
+	} else {
+		hash, err := hex.DecodeString(digest.Hex)
+		assert.NoError(t, err)
+		signature, err := ecdsa.SignASN1(rand.Reader, key, hash)
+		assert.NoError(t, err)
+		bundle = []byte(fmt.Sprintf(`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","verificationMaterial":{"publicKey":{"hint":"key"}},"messageSignature":{"messageDigest":{"algorithm":"SHA2_256","digest":%q},"signature":%q}}`, base64.StdEncoding.EncodeToString(hash), base64.StdEncoding.EncodeToString(signature)))
+	}
+	referrer, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(bundle, "application/vnd.dev.sigstore.bundle.v0.3+json"),
+	})
+	assert.NoError(t, err)
+	referrer = mutate.ConfigMediaType(mutate.MediaType(referrer, types.OCIManifestSchema1), "application/vnd.dev.sigstore.bundle.v0.3+json")
+	assert.NoError(t, p.AppendImage(mutate.Subject(referrer, *desc).(v1.Image)))
+	invalid, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer([]byte(`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json"}`), "application/vnd.dev.sigstore.bundle.v0.3+json"),
+	})
+	assert.NoError(t, err)
+	invalid = mutate.ConfigMediaType(mutate.MediaType(invalid, types.OCIManifestSchema1), "application/vnd.dev.sigstore.bundle.v0.3+json")
+	assert.NoError(t, p.AppendImage(mutate.Subject(invalid, *desc).(v1.Image)))
+	return dir, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+}
+
+func Test_Apply_Bundle(t *testing.T) {
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"ghcr.io/org/app:v1"}]}`), &resource)
+	assert.NoError(t, err)
+	dir, publicKey := writeBundleLayout(t, "")
+	l, err := registry.OpenLayout(dir)
+	assert.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherPublicKey, err := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	assert.NoError(t, err)
+	trustedRoot := `{"mediaType":"application/vnd.dev.sigstore.trustedroot+json;version=0.1"}`
+
+	tests := []struct {
+		name      string
+		publicKey string
+		wantErr   string
+	}{
+		{
+			name:      "valid key",
+			publicKey: publicKey,
+		},
+		{
+			name:      "other key",
+			publicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherPublicKey})),
+			wantErr:   "failed to verify signature",
+		},
+		{
+			name:      "invalid bundle",
+			publicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherPublicKey})),
+			wantErr:   "invalid bundle",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pol v1alpha1.ImageVerificationPolicy
+			err := json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"bundle","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/org/*"],"cosign":[{"bundle":true,"key":{"publicKey":%q},"trustedRoot":{"data":%q},"ignoreTlog":true}]}]}]}}`, tt.publicKey, trustedRoot)), &pol)
+			assert.NoError(t, err)
+
+			resp := NewEngineFromDClient(nil, WithBackend(l)).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+			rule := resp.PolicyResponses[0].RuleResponses[0]
+			if tt.wantErr == "" {
+				assert.Equal(t, PASS, rule.VerificationOutcome, rule.VerificationResults)
+			} else {
+				assert.Equal(t, FAIL, rule.VerificationOutcome)
+				assert.ErrorContains(t, rule.VerificationResults[0].VerificationResponses[0].Failures[0], tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Apply_Layout(t *testing.T) {
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"ghcr.io/org/app:v1"}]}`), &resource)
+	assert.NoError(t, err)
+
+	for _, signed := range []bool{true, false} {
+		t.Run(fmt.Sprintf("signed %t", signed), func(t *testing.T) {
+			dir, publicKey := writeSignedLayout(t, signed)
+			l, err := registry.OpenLayout(dir)
+			assert.NoError(t, err)
+			var pol v1alpha1.ImageVerificationPolicy
+			err = json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"cosign","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/org/*"],"cosign":[{"key":{"publicKey":%q},"ignoreTlog":true,"ignoreSCT":true}]}]}]}}`, publicKey)), &pol)
+			assert.NoError(t, err)
+
+			resp := NewEngineFromDClient(nil, WithBackend(l)).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+			rule := resp.PolicyResponses[0].RuleResponses[0]
+			if signed {
+				assert.Equal(t, PASS, rule.VerificationOutcome, rule.VerificationResults)
+			} else {
+				assert.Equal(t, FAIL, rule.VerificationOutcome)
+				assert.ErrorContains(t, rule.VerificationResults[0].VerificationResponses[0].Failures[0], "no signatures found")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package imageverifier
+
+import (
+	"context"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/policy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// applyExceptions turns the failed images of the rule into EXCEPTED when a policy exception waives
+// them, the outcome of the rule is aggregated again afterwards. Expired exceptions are ignored.
+func applyExceptions(ctx context.Context, ruleResponse *RuleResponse, policyName string, exceptions []*v1alpha1.PolicyException, resource interface{}, now metav1.Time) error {
+	if ruleResponse.VerificationOutcome != FAIL {
+		return nil
+	}
+	for i, result := range ruleResponse.VerificationResults {
+		if result.VerificationOutcome != FAIL {
+			continue
+		}
+		exception, err := findException(ctx, exceptions, policyName, ruleResponse.Rule.Name, result.Image, resource, now)
+		if err != nil {
+			return err
+		}
+		if exception != nil {
+			ruleResponse.VerificationResults[i].VerificationOutcome = EXCEPTED
+			ruleResponse.VerificationResults[i].Exception = exception.Name
+		}
+	}
+	ruleResponse.VerificationOutcome = aggregateOutcome(ruleResponse.VerificationResults)
+	return nil
+}
+
+// findException returns the first exception waiving the failure of the image for the rule of the
+// policy, it returns nil when no exception applies
+func findException(ctx context.Context, exceptions []*v1alpha1.PolicyException, policyName, ruleName, image string, resource interface{}, now metav1.Time) (*v1alpha1.PolicyException, error) {
+	for _, exception := range exceptions {
+		if exception.Spec.IsExpired(now) || !exceptionContains(exception, policyName, ruleName) {
+			continue
+		}
+		if len(exception.Spec.ImageReferences) > 0 && !match(exception.Spec.ImageReferences, image) {
+			continue
+		}
+		if exception.Spec.Match != nil {
+			errs, err := policy.Match(ctx, *exception.Spec.Match, resource)
+			if err != nil {
+				return nil, err
+			}
+			if len(errs) > 0 {
+				continue
+			}
+		}
+		return exception, nil
+	}
+	return nil, nil
+}
+
+func exceptionContains(exception *v1alpha1.PolicyException, policyName, ruleName string) bool {
+	for i := range exception.Spec.Exceptions {
+		if exception.Spec.Exceptions[i].Contains(policyName, ruleName) {
+			return true
+		}
+	}
+	return false
+}
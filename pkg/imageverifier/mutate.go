@@ -0,0 +1,57 @@
+package imageverifier
+
+import (
+	"github.com/kyverno/kyverno/pkg/config"
+	imageutils "github.com/kyverno/kyverno/pkg/utils/image"
+)
+
+// PatchOperation is a JSON Patch (RFC 6902) operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// mutateDigest returns true when a verification rule matching the image enables digest mutation
+func (i *imageVerifier) mutateDigest(image string) bool {
+	for _, policy := range i.rules {
+		if policy.MutateDigest && matchRule(policy, image) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinDigest returns the image reference pinned to the digest
+func pinDigest(image, digest string) (string, error) {
+	info, err := imageutils.GetImageInfo(image, config.NewDefaultConfiguration(false))
+	if err != nil {
+		return "", err
+	}
+	info.Tag = ""
+	info.Digest = digest
+	return info.String(), nil
+}
+
+// digestPatches returns the patch replacing every mutated image in the resource, when several
+// rules mutate the same field the first one wins
+func digestPatches(responses []PolicyResponse) []PatchOperation {
+	var patches []PatchOperation
+	seen := map[string]bool{}
+	for _, p := range responses {
+		for _, r := range p.RuleResponses {
+			for _, result := range r.VerificationResults {
+				if result.MutatedImage == "" || seen[result.Pointer] {
+					continue
+				}
+				seen[result.Pointer] = true
+				patches = append(patches, PatchOperation{
+					Op:    "replace",
+					Path:  result.Pointer,
+					Value: result.MutatedImage,
+				})
+			}
+		}
+	}
+	return patches
+}
@@ -0,0 +1,92 @@
+package imageverifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Apply_MutateDigest(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(host + "/test/app:v1")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+
+	var resource interface{}
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"family":"sample","containerDefinitions":[{"image":"%s/test/app:v1"},{"image":"docker.io/nginx:1.25"}]}`, host)), &resource)
+	assert.NoError(t, err)
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"pin","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["%s/*"],"mutateDigest":true}]}]}}`, host)), &pol)
+	assert.NoError(t, err)
+
+	resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+	results := resp.PolicyResponses[0].RuleResponses[0].VerificationResults
+	assert.Len(t, results, 2)
+	assert.Equal(t, PASS, results[0].VerificationOutcome)
+	assert.Equal(t, SKIP, results[1].VerificationOutcome)
+	assert.Equal(t, []PatchOperation{{
+		Op:    "replace",
+		Path:  "/containerDefinitions/0/image",
+		Value: host + "/test/app@" + digest.String(),
+	}}, resp.Patches)
+}
+
+func Test_Apply_MutateDigest_TagMoved(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(host + "/test/app:v1")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+
+	// the attestor moves the tag to another image once it verified the image
+	var verified []string
+	attestor := AttestorFunc(func(_ context.Context, request AttestorRequest) error {
+		verified = append(verified, request.Image)
+		moved, err := random.Image(1024, 1)
+		if err != nil {
+			return err
+		}
+		return remote.Write(ref, moved)
+	})
+
+	var resource interface{}
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"family":"sample","containerDefinitions":[{"image":"%s/test/app:v1"}]}`, host)), &resource)
+	assert.NoError(t, err)
+	var pol v1alpha1.ImageVerificationPolicy
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{"spec":{"rules":[{"name":"pin","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["%s/*"],"mutateDigest":true,"custom":[{"type":"signing-service"}]}]}]}}`, host)), &pol)
+	assert.NoError(t, err)
+
+	resp := NewEngineFromDClient(nil, WithAttestors(map[string]Attestor{"signing-service": attestor})).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+	result := resp.PolicyResponses[0].RuleResponses[0].VerificationResults[0]
+	assert.Equal(t, PASS, result.VerificationOutcome)
+	assert.Equal(t, digest.String(), result.Digest)
+	assert.Equal(t, []string{host + "/test/app@" + digest.String()}, verified)
+	assert.Equal(t, []PatchOperation{{
+		Op:    "replace",
+		Path:  "/containerDefinitions/0/image",
+		Value: host + "/test/app@" + digest.String(),
+	}}, resp.Patches)
+}
@@ -6,9 +6,9 @@ import (
 	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
 )
 
-func notaryVerificationOpts(n *v1alpha1.Notary, image string) (*images.Options, error) {
-	var err error
+func notaryVerificationOpts(n *v1alpha1.Notary, image string, client registryclient.Client) (*images.Options, error) {
 	opts := &images.Options{
+		Client:   client,
 		Cert:     n.Certs,
 		ImageRef: image,
 	}
@@ -17,25 +17,15 @@ func notaryVerificationOpts(n *v1alpha1.Notary, image string) (*images.Options,
 		opts.FetchAttestations = true
 	}
 
-	opts.Client, err = registryclient.New()
-	if err != nil {
-		return nil, err
-	}
-
 	return opts, nil
 }
 
-func cosignVerificationOpts(c *v1alpha1.Cosign, image string) (*images.Options, error) {
-	var err error
+func cosignVerificationOpts(c *v1alpha1.Cosign, image string, client registryclient.Client) (*images.Options, error) {
 	opts := &images.Options{
+		Client:   client,
 		ImageRef: image,
 	}
 
-	opts.Client, err = registryclient.New()
-	if err != nil {
-		return nil, err
-	}
-
 	if c.Key != nil {
 		opts.Key = c.Key.PublicKey
 	} else if c.Keyless != nil {
@@ -0,0 +1,70 @@
+package imageverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrTimeout is returned when a verification did not complete before its deadline
+var ErrTimeout = errors.New("verification timed out")
+
+// withTimeout runs verify with a context bounded by timeout, errors caused by the context
+// being done are reported as ErrTimeout or context.Canceled. Some verifications do not honour
+// the context (e.g. POST service calls), so verify runs in its own goroutine whenever the
+// context can be done and is abandoned if it does not return in time.
+func withTimeout(ctx context.Context, timeout *metav1.Duration, verify func(context.Context) error) error {
+	if timeout != nil && timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout.Duration)
+		defer cancel()
+	}
+	if ctx.Err() != nil {
+		return contextError(ctx, nil)
+	}
+	if ctx.Done() == nil {
+		return verify(ctx)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- verify(ctx)
+	}()
+	select {
+	case err := <-result:
+		return contextError(ctx, err)
+	case <-ctx.Done():
+		return contextError(ctx, nil)
+	}
+}
+
+// contextError wraps err with the reason why ctx is done, err is returned as is if ctx is not done
+func contextError(ctx context.Context, err error) error {
+	var cause error
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		cause = ErrTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		cause = context.Canceled
+	default:
+		return err
+	}
+	if err == nil || errors.Is(err, cause) || errors.Is(err, ctx.Err()) {
+		return cause
+	}
+	return fmt.Errorf("%w: %v", cause, err)
+}
+
+// errorReason returns the reason of an ERROR outcome caused by err
+func errorReason(err error) ErrorReason {
+	switch {
+	case errors.Is(err, ErrTimeout):
+		return ReasonTimeout
+	case errors.Is(err, context.Canceled):
+		return ReasonCanceled
+	case errors.Is(err, ErrUnknownAttestor):
+		return ReasonUnknownAttestor
+	}
+	return ""
+}
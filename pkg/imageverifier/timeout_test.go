@@ -0,0 +1,79 @@
+package imageverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_WithTimeout(t *testing.T) {
+	blocking := func(ctx context.Context) error {
+		<-ctx.Done()
+		return fmt.Errorf("request failed: %v", ctx.Err())
+	}
+
+	err := withTimeout(context.Background(), &metav1.Duration{Duration: 10 * time.Millisecond}, blocking)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, ReasonTimeout, errorReason(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = withTimeout(ctx, nil, blocking)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, ReasonCanceled, errorReason(err))
+
+	failure := errors.New("invalid signature")
+	err = withTimeout(context.Background(), &metav1.Duration{Duration: time.Minute}, func(context.Context) error { return failure })
+	assert.Equal(t, failure, err)
+	assert.Equal(t, ErrorReason(""), errorReason(err))
+}
+
+func Test_Apply_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	var resource interface{}
+	err := json.Unmarshal([]byte(`{"family":"sample","containerDefinitions":[{"image":"ghcr.io/example/app:v1"}]}`), &resource)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		timeout string
+	}{
+		{name: "attestor timeout", timeout: `"verify":[{"imageReferences":["ghcr.io/*"],"timeout":"50ms","externalService":[{"apiCall":{"method":"POST","service":{"url":"` + srv.URL + `"}}}]}]`},
+		{name: "rule timeout", timeout: `"timeout":"50ms","verify":[{"imageReferences":["ghcr.io/*"],"externalService":[{"apiCall":{"method":"POST","service":{"url":"` + srv.URL + `"}}}]}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pol v1alpha1.ImageVerificationPolicy
+			err := json.Unmarshal([]byte(`{"spec":{"rules":[{"name":"external","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],`+tt.timeout+`}]}}`), &pol)
+			assert.NoError(t, err)
+
+			resp := NewEngineFromDClient(nil).Apply(context.Background(), Request{Policies: []*v1alpha1.ImageVerificationPolicy{&pol}, Resource: resource})
+			ruleResp := resp.PolicyResponses[0].RuleResponses[0]
+			assert.Equal(t, ERROR, ruleResp.VerificationOutcome)
+			assert.Equal(t, ReasonTimeout, ruleResp.Reason)
+			assert.Len(t, ruleResp.VerificationResults, 1)
+			assert.Equal(t, ERROR, ruleResp.VerificationResults[0].VerificationOutcome)
+			assert.Equal(t, ReasonTimeout, ruleResp.VerificationResults[0].Reason)
+			assert.ErrorIs(t, ruleResp.VerificationResults[0].Error, ErrTimeout)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/go-logr/logr"
 	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
@@ -16,7 +17,11 @@ import (
 	"github.com/kyverno/kyverno/pkg/engine/variables"
 	"github.com/kyverno/kyverno/pkg/images"
 	"github.com/kyverno/kyverno/pkg/notary"
+	"github.com/kyverno/kyverno/pkg/registryclient"
 	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/cache"
+	"github.com/nirmata/json-image-verification/pkg/registry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -28,6 +33,19 @@ type imageVerifier struct {
 	notaryVerifier images.ImageVerifier
 	jsonCtx        enginecontext.Interface
 	jp             jmespath.Interface
+	// parallel enables evaluating the verification rules concurrently
+	parallel bool
+	// workers bounds the number of digest resolutions and verification rules evaluated at the same
+	// time, it is shared by the engine
+	workers chan struct{}
+	// cache stores the responses of verification rules by image digest, it is shared by the engine
+	cache cache.Cache
+	// registry is the registry client used by the attestors
+	registry registryclient.Client
+	// mirrors rewrites the image references pulled by the attestors
+	mirrors registry.Mirrors
+	// attestors are the custom attestors of the engine by type
+	attestors map[string]Attestor
 }
 
 func NewVerifier(rules v1alpha1.VerificationRules, client dclient.Interface, jsonCtx enginecontext.Interface, jp jmespath.Interface, count int) *imageVerifier {
@@ -42,6 +60,7 @@ func NewVerifier(rules v1alpha1.VerificationRules, client dclient.Interface, jso
 		rules:          rules,
 		cosignVerifier: cosign.NewVerifier(),
 		notaryVerifier: notary.NewVerifier(),
+		registry:       registry.NewClient(),
 	}
 }
 
@@ -54,66 +73,125 @@ func match(imgRefs []string, image string) bool {
 	return false
 }
 
-func (i *imageVerifier) Verify(image string) VerificationResult {
+// matchRule returns true when the image matches the image references of the verification rule and
+// none of its skipped image references
+func matchRule(rule v1alpha1.VerificationRule, image string) bool {
+	return match(rule.ImageReferences, image) && !match(rule.SkipImageReferences, image)
+}
+
+// isolated returns a copy of the verifier working on its own json context, so that it can be
+// used concurrently with the original verifier
+func (i *imageVerifier) isolated() (*imageVerifier, error) {
+	jsonCtx, err := cloneJsonContext(i.jsonCtx, i.jp)
+	if err != nil {
+		return nil, err
+	}
+	v := *i
+	v.jsonCtx = jsonCtx
+	return &v, nil
+}
+
+func (i *imageVerifier) Verify(ctx context.Context, image string) VerificationResult {
+	return i.verify(ctx, image, image)
+}
+
+// verify verifies the normalized image, reference is the image as found in the resource and is
+// used to enforce the digest requirements of the verification rules
+func (i *imageVerifier) verify(ctx context.Context, image, reference string) VerificationResult {
 	verificationResult := VerificationResult{
 		VerificationResponses: make([]VerificationResponse, len(i.rules)),
 		Image:                 image,
 	}
+	if source := i.mirrors.Rewrite(image); source != image {
+		verificationResult.Mirror = source
+	}
 	passedCount := 0
 	failedCount := 0
 	skippedCount := 0
 
-	for idx, policy := range i.rules {
-		verificationResp := VerificationResponse{
-			VerificationRule: policy,
-			Failures:         make([]error, 0),
-		}
-		if !match(policy.ImageReferences, image) {
-			skippedCount += 1
-			continue
+	// the digest is resolved once, the image is verified by digest so that the cached verdicts and
+	// the mutated image are the ones of the verified manifest even if the tag moves meanwhile
+	digest, verified := "", image
+	if mutate := i.mutateDigest(image); i.cache != nil || mutate {
+		if i.cache != nil {
+			verificationResult.Cache = CacheMiss
 		}
-
-		for _, cosignPolicy := range policy.Cosign {
-			if cosignPolicy == nil {
+		for _, policy := range i.rules {
+			if !matchRule(policy, image) {
 				continue
 			}
-			err := i.cosignVerification(cosignPolicy, image)
-			if err != nil {
-				verificationResp.Failures = append(verificationResp.Failures, err)
-				continue
+			var resolved string
+			release, err := i.acquire(ctx)
+			if err == nil {
+				resolved, err = resolveDigest(ctx, i.registry, i.mirrors.Rewrite(image))
+				release()
 			}
-		}
-
-		for _, notaryPolicy := range policy.Notary {
-			if notaryPolicy == nil {
-				continue
+			if err == nil {
+				verified, err = pinDigest(image, resolved)
 			}
-
-			err := i.notaryVerification(notaryPolicy, image)
 			if err != nil {
-				verificationResp.Failures = append(verificationResp.Failures, err)
-				continue
+				if mutate {
+					verificationResult.VerificationResponses = nil
+					verificationResult.VerificationOutcome = ERROR
+					verificationResult.Error = contextError(ctx, fmt.Errorf("failed to resolve digest of %s: %w", image, err))
+					verificationResult.Reason = errorReason(verificationResult.Error)
+					return verificationResult
+				}
+				// images that cannot be resolved are verified by tag without the cache
+				verified = image
+				break
 			}
+			digest = resolved
+			verificationResult.Digest = digest
+			break
 		}
+	}
 
-		for _, externalPolicy := range policy.ExternalService {
-			if externalPolicy == nil {
-				continue
-			}
+	matched := make([]bool, len(i.rules))
+	var wg sync.WaitGroup
+	for idx, policy := range i.rules {
+		if !matchRule(policy, image) {
+			continue
+		}
+		matched[idx] = true
 
-			err := i.externalServiceVerification(externalPolicy, image)
+		if !i.parallel {
+			verificationResult.VerificationResponses[idx] = i.verifyRuleWithDigest(ctx, policy, verified, reference, digest)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := i.isolated()
 			if err != nil {
-				verificationResp.Failures = append(verificationResp.Failures, err)
-				continue
+				verificationResult.VerificationResponses[idx] = VerificationResponse{
+					VerificationRule: policy,
+					Failures:         []error{fmt.Errorf("failed to copy json context: %w", err)},
+				}
+				return
 			}
-		}
+			verificationResult.VerificationResponses[idx] = v.verifyRuleWithDigest(ctx, policy, verified, reference, digest)
+		}()
+	}
+	wg.Wait()
 
-		if len(verificationResp.Failures) == 0 {
+	cached := 0
+	for idx := range i.rules {
+		if matched[idx] && verificationResult.VerificationResponses[idx].Cached {
+			cached += 1
+		}
+		if !matched[idx] {
+			skippedCount += 1
+		} else if len(verificationResult.VerificationResponses[idx].Failures) == 0 {
 			passedCount += 1
 		} else {
 			failedCount += 1
 		}
-		verificationResult.VerificationResponses[idx] = verificationResp
+	}
+
+	if cached > 0 && cached == passedCount+failedCount {
+		verificationResult.Cache = CacheHit
 	}
 
 	if passedCount >= i.count {
@@ -124,17 +202,128 @@ func (i *imageVerifier) Verify(image string) VerificationResult {
 		verificationResult.VerificationOutcome = FAIL
 	}
 
+	// a verification that could not complete in time is an error rather than a failure
+	if verificationResult.VerificationOutcome == FAIL {
+		for _, resp := range verificationResult.VerificationResponses {
+			for _, err := range resp.Failures {
+				if reason := errorReason(err); reason != "" {
+					verificationResult.VerificationOutcome = ERROR
+					verificationResult.Error = err
+					verificationResult.Reason = reason
+					return verificationResult
+				}
+			}
+		}
+	}
+
 	return verificationResult
 }
 
-func (i *imageVerifier) cosignVerification(pol *v1alpha1.Cosign, image string) error {
-	opts, err := cosignVerificationOpts(pol, image)
+func (i *imageVerifier) verifyRule(ctx context.Context, policy v1alpha1.VerificationRule, image string) VerificationResponse {
+	verificationResp := VerificationResponse{
+		VerificationRule: policy,
+		Failures:         make([]error, 0),
+	}
+
+	for _, cosignPolicy := range policy.Cosign {
+		if cosignPolicy == nil {
+			continue
+		}
+		err := i.attest(ctx, policy.Timeout, func(ctx context.Context, v *imageVerifier) error {
+			return v.cosignVerification(ctx, cosignPolicy, v.mirrors.Rewrite(image))
+		})
+		if err != nil {
+			verificationResp.Failures = append(verificationResp.Failures, err)
+			continue
+		}
+	}
+
+	for _, notaryPolicy := range policy.Notary {
+		if notaryPolicy == nil {
+			continue
+		}
+
+		err := i.attest(ctx, policy.Timeout, func(ctx context.Context, v *imageVerifier) error {
+			return v.notaryVerification(ctx, notaryPolicy, v.mirrors.Rewrite(image))
+		})
+		if err != nil {
+			verificationResp.Failures = append(verificationResp.Failures, err)
+			continue
+		}
+	}
+
+	for _, externalPolicy := range policy.ExternalService {
+		if externalPolicy == nil {
+			continue
+		}
+
+		err := i.attest(ctx, policy.Timeout, func(ctx context.Context, v *imageVerifier) error {
+			return v.externalServiceVerification(ctx, externalPolicy, image)
+		})
+		if err != nil {
+			verificationResp.Failures = append(verificationResp.Failures, err)
+			continue
+		}
+	}
+
+	for _, customPolicy := range policy.Custom {
+		if customPolicy == nil {
+			continue
+		}
+
+		err := i.attest(ctx, policy.Timeout, func(ctx context.Context, v *imageVerifier) error {
+			return v.customVerification(ctx, customPolicy, v.mirrors.Rewrite(image))
+		})
+		if err != nil {
+			verificationResp.Failures = append(verificationResp.Failures, err)
+			continue
+		}
+	}
+
+	return verificationResp
+}
+
+// acquire takes a worker of the engine before calling the registries, release gives it back. The
+// workers are taken one at a time so that the goroutines holding one never wait for another.
+func (i *imageVerifier) acquire(ctx context.Context) (release func(), err error) {
+	if i.workers == nil {
+		return func() {}, nil
+	}
+	select {
+	case i.workers <- struct{}{}:
+		return func() { <-i.workers }, nil
+	case <-ctx.Done():
+		return nil, contextError(ctx, nil)
+	}
+}
+
+// attest runs a single attestor within timeout. When the verification may be abandoned before it
+// returns, it runs on an isolated copy of the verifier so that it cannot alter the json context.
+func (i *imageVerifier) attest(ctx context.Context, timeout *metav1.Duration, verify func(context.Context, *imageVerifier) error) error {
+	v := i
+	if ctx.Done() != nil || (timeout != nil && timeout.Duration > 0) {
+		var err error
+		if v, err = i.isolated(); err != nil {
+			return fmt.Errorf("failed to copy json context: %w", err)
+		}
+	}
+	return withTimeout(ctx, timeout, func(ctx context.Context) error {
+		return verify(ctx, v)
+	})
+}
+
+func (i *imageVerifier) cosignVerification(ctx context.Context, pol *v1alpha1.Cosign, image string) error {
+	if pol.Bundle {
+		return i.bundleVerification(ctx, pol, image)
+	}
+
+	opts, err := cosignVerificationOpts(pol, image, i.registry)
 	if err != nil {
 		return err
 	}
 
 	if len(pol.InToToAttestations) == 0 {
-		_, err = i.cosignVerifier.VerifySignature(context.TODO(), *opts)
+		_, err = i.cosignVerifier.VerifySignature(ctx, *opts)
 		if err != nil {
 			return err
 		}
@@ -147,7 +336,7 @@ func (i *imageVerifier) cosignVerification(pol *v1alpha1.Cosign, image string) e
 
 		o := *opts
 		o.Type = att.Type
-		resp, err := i.cosignVerifier.FetchAttestations(context.Background(), o)
+		resp, err := i.cosignVerifier.FetchAttestations(ctx, o)
 		if err != nil {
 			return err
 		}
@@ -157,7 +346,7 @@ func (i *imageVerifier) cosignVerification(pol *v1alpha1.Cosign, image string) e
 			return fmt.Errorf("failed to check attestations: %w", err)
 		}
 		if !val {
-			return fmt.Errorf("attestation checks failed for %s and predicate %s: %s", image, att.Type, msg)
+			return verificationFailed("attestation checks failed for %s and predicate %s: %s", image, att.Type, msg)
 		}
 	}
 	return nil
@@ -174,14 +363,14 @@ func (i *imageVerifier) filterStatements(predicateType string, resp *images.Resp
 	return resp
 }
 
-func (i *imageVerifier) notaryVerification(pol *v1alpha1.Notary, image string) error {
-	opts, err := notaryVerificationOpts(pol, image)
+func (i *imageVerifier) notaryVerification(ctx context.Context, pol *v1alpha1.Notary, image string) error {
+	opts, err := notaryVerificationOpts(pol, image, i.registry)
 	if err != nil {
 		return err
 	}
 
 	if len(pol.Attestations) == 0 {
-		_, err = i.notaryVerifier.VerifySignature(context.Background(), *opts)
+		_, err = i.notaryVerifier.VerifySignature(ctx, *opts)
 		if err != nil {
 			return err
 		}
@@ -194,7 +383,7 @@ func (i *imageVerifier) notaryVerification(pol *v1alpha1.Notary, image string) e
 
 		o := *opts
 		o.Type = att.Type
-		resp, err := i.notaryVerifier.FetchAttestations(context.Background(), o)
+		resp, err := i.notaryVerifier.FetchAttestations(ctx, o)
 		if err != nil {
 			return err
 		}
@@ -203,13 +392,13 @@ func (i *imageVerifier) notaryVerification(pol *v1alpha1.Notary, image string) e
 			return fmt.Errorf("failed to check attestations: %w", err)
 		}
 		if !val {
-			return fmt.Errorf("attestation checks failed for %s and predicate %s: %s", image, att.Type, msg)
+			return verificationFailed("attestation checks failed for %s and predicate %s: %s", image, att.Type, msg)
 		}
 	}
 	return nil
 }
 
-func (i *imageVerifier) externalServiceVerification(pol *v1alpha1.ExternalService, image string) error {
+func (i *imageVerifier) externalServiceVerification(ctx context.Context, pol *v1alpha1.ExternalService, image string) error {
 	executor, err := apicall.New(
 		logr.Discard(),
 		i.jp,
@@ -224,7 +413,7 @@ func (i *imageVerifier) externalServiceVerification(pol *v1alpha1.ExternalServic
 		return nil
 	}
 
-	data, err := executor.Execute(context.TODO(), pol.APICall)
+	data, err := executor.Execute(ctx, pol.APICall)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package imageverifier
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -83,7 +84,7 @@ func Test_Verifier(t *testing.T) {
 			}
 
 			verifier := NewVerifier(ivRules, nil, jsonContext, jp, 1)
-			if resp := verifier.Verify(tt.image); resp.VerificationOutcome != tt.wantOutcome {
+			if resp := verifier.Verify(context.Background(), tt.image); resp.VerificationOutcome != tt.wantOutcome {
 				t.Errorf("verifier test failed, want: %v, got: %v", tt.wantOutcome, resp.VerificationOutcome)
 				for _, r := range resp.VerificationResponses {
 					t.Errorf("err: %v", r.Failures)
@@ -12,6 +12,20 @@ import (
 	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
 )
 
+// ImageReference is an image extracted from a resource
+type ImageReference struct {
+	// Image is the normalized image reference
+	Image string
+	// Reference is the image reference as found in the resource, unlike Image it keeps the
+	// tag of references pinned to a digest
+	Reference string
+	// Pointer is the JSON pointer (RFC 6901) of the image field in the resource
+	Pointer string
+	// Mutable is false when the image was transformed by a JMESPath expression, the field
+	// in the resource does not hold the image reference as is and must not be mutated
+	Mutable bool
+}
+
 type imageExtractor struct {
 	Fields   []string
 	Key      string
@@ -20,8 +34,8 @@ type imageExtractor struct {
 	JMESPath string
 }
 
-func (i *imageExtractor) ExtractFromResource(resource interface{}, cfg config.Configuration) (map[string]string, error) {
-	imageInfo := map[string]string{}
+func (i *imageExtractor) ExtractFromResource(resource interface{}, cfg config.Configuration) (map[string]ImageReference, error) {
+	imageInfo := map[string]ImageReference{}
 	if err := extract(resource, []string{}, i.Key, i.Value, i.Fields, i.JMESPath, &imageInfo, cfg); err != nil {
 		return nil, err
 	}
@@ -35,7 +49,7 @@ func extract(
 	valuePath string,
 	fields []string,
 	jmesPath string,
-	imageInfos *map[string]string,
+	imageInfos *map[string]ImageReference,
 	cfg config.Configuration,
 ) error {
 	if obj == nil {
@@ -99,7 +113,12 @@ func extract(
 		if imageInfo, err := imageutils.GetImageInfo(value, cfg); err != nil {
 			return fmt.Errorf("invalid image '%s' (%s)", value, err.Error())
 		} else {
-			(*imageInfos)[key] = imageInfo.String()
+			(*imageInfos)[key] = ImageReference{
+				Image:     imageInfo.String(),
+				Reference: value,
+				Pointer:   jsonPointer(append(path, valuePath)),
+				Mutable:   jmesPath == "",
+			}
 		}
 		return nil
 	}
@@ -107,6 +126,16 @@ func extract(
 	return extract(output[currentPath], append(path, currentPath), keyPath, valuePath, fields[1:], jmesPath, imageInfos, cfg)
 }
 
+// jsonPointer escapes the path segments according to RFC 6901
+func jsonPointer(path []string) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	segments := make([]string, 0, len(path))
+	for _, p := range path {
+		segments = append(segments, escaper.Replace(p))
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
 func lookupImageExtractors(configs v1alpha1.ImageExtractorConfigs) []imageExtractor {
 	extractors := []imageExtractor{}
 	for _, c := range configs {
@@ -140,10 +169,25 @@ func lookupImageExtractors(configs v1alpha1.ImageExtractorConfigs) []imageExtrac
 	return extractors
 }
 
+// GetImages returns the images extracted from the resource keyed by extractor key or JSON pointer
 func GetImages(resource interface{}, configs v1alpha1.ImageExtractorConfigs) (map[string]string, error) {
+	refs, err := ExtractImages(resource, configs)
+	if err != nil {
+		return nil, err
+	}
+	info := make(map[string]string, len(refs))
+	for k, v := range refs {
+		info[k] = v.Image
+	}
+	return info, nil
+}
+
+// ExtractImages returns the images extracted from the resource keyed by extractor key or JSON pointer,
+// along with their location in the resource
+func ExtractImages(resource interface{}, configs v1alpha1.ImageExtractorConfigs) (map[string]ImageReference, error) {
 	cfg := config.NewDefaultConfiguration(false)
 	extractors := lookupImageExtractors(configs)
-	info := map[string]string{}
+	info := map[string]ImageReference{}
 
 	for _, extractor := range extractors {
 		img, err := extractor.ExtractFromResource(resource, cfg)
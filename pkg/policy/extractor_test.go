@@ -116,3 +116,18 @@ func Test_Extractor(t *testing.T) {
 		})
 	}
 }
+
+func Test_ExtractImages(t *testing.T) {
+	object := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{"containers":{"a/b":{"image":"docker://nginx:1.25"}},"sidecars":[{"name":"proxy","image":"envoy:v1"}]}`), &object); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ExtractImages(object, v1alpha1.ImageExtractorConfigs{
+		{Path: "/containers/*/image/", JMESPath: "trim_prefix(@, 'docker://')"},
+		{Path: "/sidecars/*/", Key: "name", Value: "image"},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(got), 2)
+	assert.Equal(t, got["/containers/a/b/image"], ImageReference{Image: "docker.io/nginx:1.25", Reference: "nginx:1.25", Pointer: "/containers/a~1b/image", Mutable: false})
+	assert.Equal(t, got["proxy"], ImageReference{Image: "docker.io/envoy:v1", Reference: "envoy:v1", Pointer: "/sidecars/0/image", Mutable: true})
+}
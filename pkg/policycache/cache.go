@@ -0,0 +1,156 @@
+package policycache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	informers "github.com/nirmata/json-image-verification/pkg/client/informers/externalversions/apis/v1alpha1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// EventType is the type of a policy change
+type EventType string
+
+const (
+	Added   EventType = "Added"
+	Updated EventType = "Updated"
+	Deleted EventType = "Deleted"
+)
+
+// Event is a change of a policy in the cluster. Err is set when the policy failed to load, the
+// policy is not used until it is fixed.
+type Event struct {
+	Type   EventType
+	Name   string
+	Policy *v1alpha1.ImageVerificationPolicy
+	Err    error
+}
+
+// Handler is notified of the policy changes, handlers are called sequentially in the order the
+// changes are observed and must not block
+type Handler func(Event)
+
+// Cache keeps the valid policies of the cluster in memory, it is backed by a shared informer
+type Cache struct {
+	lock     sync.RWMutex
+	policies map[string]*v1alpha1.ImageVerificationPolicy
+	errors   map[string]error
+	handlers []Handler
+	synced   cache.InformerSynced
+}
+
+// New creates a cache populated by the informer, the informer must be started by the caller
+func New(informer informers.ImageVerificationPolicyInformer) (*Cache, error) {
+	c := &Cache{
+		policies: map[string]*v1alpha1.ImageVerificationPolicy{},
+		errors:   map[string]error{},
+	}
+	registration, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if p, ok := obj.(*v1alpha1.ImageVerificationPolicy); ok {
+				c.set(Added, p)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, ok := oldObj.(*v1alpha1.ImageVerificationPolicy)
+			if !ok {
+				return
+			}
+			p, ok := newObj.(*v1alpha1.ImageVerificationPolicy)
+			if !ok || p.ResourceVersion == old.ResourceVersion {
+				// periodic resyncs do not change the policy
+				return
+			}
+			c.set(Updated, p)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if p, ok := obj.(*v1alpha1.ImageVerificationPolicy); ok {
+				c.delete(p)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register policy event handler: %w", err)
+	}
+	c.synced = registration.HasSynced
+	return c, nil
+}
+
+// AddHandler registers a handler notified of the policy changes observed after the registration
+func (c *Cache) AddHandler(handler Handler) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handlers = append(c.handlers, handler)
+}
+
+// Policies returns the valid policies sorted by name, the policies are shared and must not be modified
+func (c *Cache) Policies() []*v1alpha1.ImageVerificationPolicy {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	policies := make([]*v1alpha1.ImageVerificationPolicy, 0, len(c.policies))
+	for _, p := range c.policies {
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	return policies
+}
+
+// Errors returns the load error of every invalid policy by name
+func (c *Cache) Errors() map[string]error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	errs := make(map[string]error, len(c.errors))
+	for name, err := range c.errors {
+		errs[name] = err
+	}
+	return errs
+}
+
+// HasSynced returns true once the initial list of policies has been loaded
+func (c *Cache) HasSynced() bool {
+	return c.synced()
+}
+
+// WaitForCacheSync waits for the initial list of policies to be loaded, it returns false when ctx
+// is done before
+func (c *Cache) WaitForCacheSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), c.synced)
+}
+
+func (c *Cache) set(eventType EventType, p *v1alpha1.ImageVerificationPolicy) {
+	err := p.Validate().ToAggregate()
+	c.lock.Lock()
+	if err != nil {
+		delete(c.policies, p.Name)
+		c.errors[p.Name] = err
+	} else {
+		c.policies[p.Name] = p
+		delete(c.errors, p.Name)
+	}
+	handlers := c.handlers
+	c.lock.Unlock()
+	notify(handlers, Event{Type: eventType, Name: p.Name, Policy: p, Err: err})
+}
+
+func (c *Cache) delete(p *v1alpha1.ImageVerificationPolicy) {
+	c.lock.Lock()
+	delete(c.policies, p.Name)
+	delete(c.errors, p.Name)
+	handlers := c.handlers
+	c.lock.Unlock()
+	notify(handlers, Event{Type: Deleted, Name: p.Name, Policy: p})
+}
+
+func notify(handlers []Handler, event Event) {
+	for _, h := range handlers {
+		h(event)
+	}
+}
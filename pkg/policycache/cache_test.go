@@ -0,0 +1,101 @@
+package policycache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/client/clientset/versioned/fake"
+	"github.com/nirmata/json-image-verification/pkg/client/informers/externalversions"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPolicy(name string, verify ...v1alpha1.VerificationRule) *v1alpha1.ImageVerificationPolicy {
+	return &v1alpha1.ImageVerificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.ImageVerificationPolicySpec{
+			Rules: []v1alpha1.ImageVerificationRule{{Name: "rule", Rules: verify}},
+		},
+	}
+}
+
+func invalid() v1alpha1.VerificationRule {
+	return v1alpha1.VerificationRule{
+		ImageReferences: []string{"*"},
+		Cosign:          []*v1alpha1.Cosign{{Key: &v1alpha1.Key{}, Keyless: &v1alpha1.Keyless{}}},
+	}
+}
+
+func names(policies []*v1alpha1.ImageVerificationPolicy) []string {
+	var names []string
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func Test_Cache(t *testing.T) {
+	client := fake.NewSimpleClientset(newPolicy("b"), newPolicy("a"), newPolicy("broken", invalid()))
+	factory := externalversions.NewSharedInformerFactory(client, 0)
+	c, err := New(factory.Nirmata().V1alpha1().ImageVerificationPolicies())
+	assert.NoError(t, err)
+	events := make(chan Event, 10)
+	c.AddHandler(func(e Event) { events <- e })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	assert.True(t, c.WaitForCacheSync(ctx))
+	assert.True(t, c.HasSynced())
+	assert.Equal(t, []string{"a", "b"}, names(c.Policies()))
+	assert.Len(t, c.Errors(), 1)
+	assert.ErrorContains(t, c.Errors()["broken"], "spec.rules[0].verify[0]")
+	for i := 0; i < 3; i++ {
+		<-events
+	}
+
+	next := func() Event {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+			return Event{}
+		}
+	}
+
+	// fixing a policy makes it available
+	fixed := newPolicy("broken")
+	fixed.ResourceVersion = "2"
+	_, err = client.NirmataV1alpha1().ImageVerificationPolicies().Update(ctx, fixed, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	e := next()
+	assert.Equal(t, Updated, e.Type)
+	assert.Equal(t, "broken", e.Name)
+	assert.NoError(t, e.Err)
+	assert.Equal(t, []string{"a", "b", "broken"}, names(c.Policies()))
+	assert.Empty(t, c.Errors())
+
+	// breaking a policy removes it
+	broken := newPolicy("a", invalid())
+	broken.ResourceVersion = "3"
+	_, err = client.NirmataV1alpha1().ImageVerificationPolicies().Update(ctx, broken, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	e = next()
+	assert.Equal(t, Updated, e.Type)
+	assert.Error(t, e.Err)
+	assert.Equal(t, []string{"b", "broken"}, names(c.Policies()))
+
+	assert.NoError(t, client.NirmataV1alpha1().ImageVerificationPolicies().Delete(ctx, "a", metav1.DeleteOptions{}))
+	e = next()
+	assert.Equal(t, Deleted, e.Type)
+	assert.Empty(t, c.Errors())
+
+	_, err = client.NirmataV1alpha1().ImageVerificationPolicies().Create(ctx, newPolicy("c"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	e = next()
+	assert.Equal(t, Added, e.Type)
+	assert.Equal(t, []string{"b", "broken", "c"}, names(c.Policies()))
+}
@@ -0,0 +1,175 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	ecr "github.com/awslabs/amazon-ecr-credential-helper/ecr-login"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/authn/github"
+	kauth "github.com/google/go-containerregistry/pkg/authn/kubernetes"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/google"
+	"github.com/kyverno/kyverno/pkg/registryclient"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CredentialHelpers lists the supported credential helpers. The default helper uses the docker
+// configuration of the user, the others use the ambient credentials of the cloud providers.
+var CredentialHelpers = []string{"default", "amazon", "azure", "google", "github"}
+
+// Credential is a static credential of a registry
+type Credential struct {
+	// Registry is the host of the registry, e.g. ghcr.io or registry.example.com:5000
+	Registry string `json:"registry"`
+	// Username and Password authenticate with basic authentication
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Token is a bearer token sent to the registry instead of the username and password
+	Token string `json:"token,omitempty"`
+}
+
+// Auth configures the credentials used to access the registries. For every registry the static
+// credentials are used first, then the docker configuration and then the credential helpers, and
+// the registry is accessed anonymously when none of them has credentials.
+type Auth struct {
+	// Credentials are the static credentials by registry
+	Credentials []Credential
+	// DockerConfig is the path of a docker config.json file, the credentials stores and the
+	// credential helpers it configures are used
+	DockerConfig string
+	// CredentialHelpers are the names of the credential helpers, see CredentialHelpers
+	CredentialHelpers []string
+}
+
+// Keychain returns the keychain resolving the credentials configured by auth
+func (a Auth) Keychain() (authn.Keychain, error) {
+	var keychains []authn.Keychain
+	if len(a.Credentials) > 0 {
+		kc, err := newStaticKeychain(a.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		keychains = append(keychains, kc)
+	}
+	if a.DockerConfig != "" {
+		kc, err := newDockerConfigKeychain(a.DockerConfig)
+		if err != nil {
+			return nil, err
+		}
+		keychains = append(keychains, kc)
+	}
+	for _, helper := range a.CredentialHelpers {
+		switch helper {
+		case "default":
+			keychains = append(keychains, authn.DefaultKeychain)
+		case "amazon":
+			keychains = append(keychains, authn.NewKeychainFromHelper(ecr.NewECRHelper(ecr.WithLogger(io.Discard))))
+		case "azure":
+			keychains = append(keychains, registryclient.AzureKeychain)
+		case "google":
+			keychains = append(keychains, google.Keychain)
+		case "github":
+			keychains = append(keychains, github.Keychain)
+		default:
+			return nil, fmt.Errorf("unsupported credential helper %q, must be one of %v", helper, CredentialHelpers)
+		}
+	}
+	return authn.NewMultiKeychain(keychains...), nil
+}
+
+// staticKeychain resolves the static credentials of the registries
+type staticKeychain map[string]authn.Authenticator
+
+func newStaticKeychain(credentials []Credential) (staticKeychain, error) {
+	kc := staticKeychain{}
+	for _, c := range credentials {
+		registry, err := name.NewRegistry(c.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry %q: %w", c.Registry, err)
+		}
+		switch {
+		case c.Token != "" && (c.Username != "" || c.Password != ""):
+			return nil, fmt.Errorf("credential of registry %s must set either a token or a username and password", c.Registry)
+		case c.Token != "":
+			kc[registry.RegistryStr()] = authn.FromConfig(authn.AuthConfig{RegistryToken: c.Token})
+		case c.Username != "":
+			kc[registry.RegistryStr()] = authn.FromConfig(authn.AuthConfig{Username: c.Username, Password: c.Password})
+		default:
+			return nil, fmt.Errorf("credential of registry %s must set a token or a username", c.Registry)
+		}
+	}
+	return kc, nil
+}
+
+func (kc staticKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if auth, ok := kc[target.RegistryStr()]; ok {
+		return auth, nil
+	}
+	return authn.Anonymous, nil
+}
+
+// dockerConfigKeychain resolves the credentials of a docker configuration file
+type dockerConfigKeychain struct {
+	config *configfile.ConfigFile
+}
+
+func newDockerConfigKeychain(path string) (*dockerConfigKeychain, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker config: %w", err)
+	}
+	defer f.Close()
+	cf, err := config.LoadFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse docker config %s: %w", path, err)
+	}
+	return &dockerConfigKeychain{config: cf}, nil
+}
+
+func (kc *dockerConfigKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	for _, key := range []string{target.String(), target.RegistryStr()} {
+		if key == name.DefaultRegistry {
+			key = authn.DefaultAuthKey
+		}
+		cfg, err := kc.config.GetAuthConfig(key)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Username != "" || cfg.Password != "" || cfg.Auth != "" || cfg.IdentityToken != "" || cfg.RegistryToken != "" {
+			return authn.FromConfig(authn.AuthConfig{
+				Username:      cfg.Username,
+				Password:      cfg.Password,
+				Auth:          cfg.Auth,
+				IdentityToken: cfg.IdentityToken,
+				RegistryToken: cfg.RegistryToken,
+			}), nil
+		}
+	}
+	return authn.Anonymous, nil
+}
+
+// PullSecretsKeychain returns the keychain resolving the credentials of the image pull secrets
+func PullSecretsKeychain(ctx context.Context, client kubernetes.Interface, secrets []v1alpha1.ImagePullSecret) (authn.Keychain, error) {
+	if client == nil {
+		return nil, errors.New("image pull secrets require a Kubernetes client")
+	}
+	pullSecrets := make([]corev1.Secret, 0, len(secrets))
+	for _, s := range secrets {
+		secret, err := client.CoreV1().Secrets(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image pull secret %s/%s: %w", s.Namespace, s.Name, err)
+		}
+		pullSecrets = append(pullSecrets, *secret)
+	}
+	return kauth.NewFromPullSecrets(ctx, pullSecrets)
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func resolve(t *testing.T, kc authn.Keychain, image string) *authn.AuthConfig {
+	t.Helper()
+	ref, err := name.ParseReference(image)
+	assert.NoError(t, err)
+	auth, err := kc.Resolve(ref.Context())
+	assert.NoError(t, err)
+	cfg, err := auth.Authorization()
+	assert.NoError(t, err)
+	return cfg
+}
+
+func Test_Auth_Credentials(t *testing.T) {
+	kc, err := Auth{Credentials: []Credential{
+		{Registry: "registry.example.com:5000", Username: "user", Password: "pass"},
+		{Registry: "ghcr.io", Token: "token"},
+	}}.Keychain()
+	assert.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "user", Password: "pass"}, resolve(t, kc, "registry.example.com:5000/app:v1"))
+	assert.Equal(t, &authn.AuthConfig{RegistryToken: "token"}, resolve(t, kc, "ghcr.io/org/app:v1"))
+	assert.Equal(t, &authn.AuthConfig{}, resolve(t, kc, "docker.io/library/nginx:latest"))
+}
+
+func Test_Auth_InvalidCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		credential Credential
+		wantErr    string
+	}{{
+		name:       "invalid registry",
+		credential: Credential{Registry: "Registry.Example.com/app", Username: "user"},
+		wantErr:    `invalid registry "Registry.Example.com/app"`,
+	}, {
+		name:       "token and username",
+		credential: Credential{Registry: "ghcr.io", Username: "user", Token: "token"},
+		wantErr:    "credential of registry ghcr.io must set either a token or a username and password",
+	}, {
+		name:       "no credential",
+		credential: Credential{Registry: "ghcr.io"},
+		wantErr:    "credential of registry ghcr.io must set a token or a username",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Auth{Credentials: []Credential{tt.credential}}.Keychain()
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_Auth_DockerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	assert.NoError(t, os.WriteFile(path, []byte(`{"auths":{"registry.example.com":{"auth":"`+auth+`"},"https://index.docker.io/v1/":{"auth":"`+auth+`"}}}`), 0o600))
+
+	kc, err := Auth{DockerConfig: path}.Keychain()
+	assert.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "user", Password: "pass"}, resolve(t, kc, "registry.example.com/app:v1"))
+	assert.Equal(t, &authn.AuthConfig{Username: "user", Password: "pass"}, resolve(t, kc, "nginx:latest"))
+	assert.Equal(t, &authn.AuthConfig{}, resolve(t, kc, "ghcr.io/org/app:v1"))
+
+	_, err = Auth{DockerConfig: filepath.Join(t.TempDir(), "missing.json")}.Keychain()
+	assert.ErrorContains(t, err, "failed to read docker config")
+}
+
+func Test_Auth_StaticCredentialsFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	assert.NoError(t, os.WriteFile(path, []byte(`{"auths":{"registry.example.com":{"auth":"`+auth+`"}}}`), 0o600))
+
+	kc, err := Auth{
+		Credentials:  []Credential{{Registry: "registry.example.com", Token: "token"}},
+		DockerConfig: path,
+	}.Keychain()
+	assert.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{RegistryToken: "token"}, resolve(t, kc, "registry.example.com/app:v1"))
+}
+
+func Test_Auth_CredentialHelpers(t *testing.T) {
+	_, err := Auth{CredentialHelpers: CredentialHelpers}.Keychain()
+	assert.NoError(t, err)
+	_, err = Auth{CredentialHelpers: []string{"unknown"}}.Keychain()
+	assert.ErrorContains(t, err, `unsupported credential helper "unknown"`)
+}
+
+func Test_PullSecretsKeychain(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"auth":"` + auth + `"}}}`),
+		},
+	})
+
+	kc, err := PullSecretsKeychain(context.Background(), client, []v1alpha1.ImagePullSecret{{Name: "regcred", Namespace: "default"}})
+	assert.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "user", Password: "pass"}, resolve(t, kc, "registry.example.com/app:v1"))
+
+	_, err = PullSecretsKeychain(context.Background(), client, []v1alpha1.ImagePullSecret{{Name: "missing", Namespace: "default"}})
+	assert.ErrorContains(t, err, "failed to get image pull secret default/missing")
+
+	_, err = PullSecretsKeychain(context.Background(), nil, []v1alpha1.ImagePullSecret{{Name: "regcred", Namespace: "default"}})
+	assert.ErrorContains(t, err, "image pull secrets require a Kubernetes client")
+}
@@ -0,0 +1,33 @@
+package registry
+
+import (
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/kyverno/kyverno/pkg/registryclient"
+)
+
+// Backend provides the registry clients the attestors resolve images, signatures and attestations with
+type Backend interface {
+	// Client returns a registry client resolving the credentials of the registries with the
+	// keychain, registries are accessed anonymously when the keychain is nil
+	Client(keychain authn.Keychain) registryclient.Client
+}
+
+// Remote is the backend of the live registries
+type Remote struct {
+	// Transport is the transport used to access the registries, see Transport. The default
+	// transport is used when nil.
+	Transport http.RoundTripper
+}
+
+func (r Remote) Client(keychain authn.Keychain) registryclient.Client {
+	var opts []Option
+	if keychain != nil {
+		opts = append(opts, WithKeychain(keychain))
+	}
+	if r.Transport != nil {
+		opts = append(opts, WithTransport(r.Transport))
+	}
+	return NewClient(opts...)
+}
@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kyverno/kyverno/pkg/registryclient"
+)
+
+// userAgent is sent with every registry request
+const userAgent = "json-image-verification"
+
+// Option configures the registry client
+type Option func(*client)
+
+// WithKeychain sets the keychain resolving the credentials of the registries, registries are
+// accessed anonymously by default
+func WithKeychain(keychain authn.Keychain) Option {
+	return func(c *client) {
+		c.keychain = keychain
+	}
+}
+
+// WithTransport sets the transport used to access the registries, see Transport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *client) {
+		c.transport = transport
+	}
+}
+
+// client is a registry client using the configured credentials. It implements the registry client
+// interface of Kyverno so that it is used by every attestor.
+type client struct {
+	// Client is only embedded to implement the unexported methods of the interface, all the
+	// exported methods are implemented by the client
+	registryclient.Client
+	keychain  authn.Keychain
+	transport http.RoundTripper
+}
+
+// NewClient creates a registry client
+func NewClient(opts ...Option) registryclient.Client {
+	c := &client{
+		Client:    registryclient.NewOrDie(),
+		keychain:  authn.NewMultiKeychain(),
+		transport: defaultTransport(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// defaultTransport returns the transport used by the client, it matches the transport of the
+// Kyverno registry client
+func defaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func (c *client) Keychain() authn.Keychain {
+	return c.keychain
+}
+
+func (c *client) Options(ctx context.Context) ([]gcrremote.Option, error) {
+	opts := []gcrremote.Option{
+		gcrremote.WithAuthFromKeychain(c.keychain),
+		gcrremote.WithTransport(c.transport),
+		gcrremote.WithContext(ctx),
+		gcrremote.WithUserAgent(userAgent),
+	}
+	puller, err := gcrremote.NewPuller(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, gcrremote.Reuse(puller)), nil
+}
+
+func (c *client) FetchImageDescriptor(ctx context.Context, imageRef string) (*gcrremote.Descriptor, error) {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %s: %w", imageRef, err)
+	}
+	opts, err := c.Options(ctx)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := gcrremote.Get(ref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image reference %s: %w", imageRef, err)
+	}
+	if _, ok := ref.(name.Digest); ok && ref.Identifier() != desc.Digest.String() {
+		return nil, fmt.Errorf("digest mismatch, expected: %s, received: %s", ref.Identifier(), desc.Digest.String())
+	}
+	return desc, nil
+}
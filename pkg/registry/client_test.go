@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+// basicAuth requires the username and password for every request once enabled
+func basicAuth(handler http.Handler, enabled *bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); *enabled && (!ok || user != "user" || pass != "pass") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func Test_Client_FetchImageDescriptor(t *testing.T) {
+	enabled := false
+	srv := httptest.NewServer(basicAuth(registry.New(), &enabled))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(host + "/test/app:v1")
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+	enabled = true
+
+	_, err = NewClient().FetchImageDescriptor(context.Background(), ref.String())
+	assert.ErrorContains(t, err, "401 Unauthorized")
+
+	kc, err := Auth{Credentials: []Credential{{Registry: host, Username: "user", Password: "pass"}}}.Keychain()
+	assert.NoError(t, err)
+	desc, err := NewClient(WithKeychain(kc)).FetchImageDescriptor(context.Background(), ref.String())
+	assert.NoError(t, err)
+	assert.Equal(t, digest, desc.Digest)
+
+}
@@ -0,0 +1,379 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kyverno/kyverno/pkg/registryclient"
+)
+
+const (
+	// refNameAnnotation is the tag or the reference of the manifests of the index of a layout
+	refNameAnnotation = "org.opencontainers.image.ref.name"
+	// cosignKindAnnotation is the kind of the manifests of the layouts written by cosign save, the
+	// tags of the signatures and the attestations are derived from the digest of the saved image
+	cosignKindAnnotation = "kind"
+	cosignImageKind      = "dev.cosignproject.cosign/image"
+	cosignImageIndexKind = "dev.cosignproject.cosign/imageIndex"
+	cosignSigsKind       = "dev.cosignproject.cosign/sigs"
+	cosignAttsKind       = "dev.cosignproject.cosign/atts"
+)
+
+// Layout is the backend of an OCI image layout, so that images are verified without registry
+// access. The layout is a directory or an uncompressed tarball, e.g. written by cosign save or
+// oras copy --to-oci-layout, and it serves the registry API to the attestors for every registry
+// and repository:
+//   - manifests and blobs by digest
+//   - manifests by tag, the tag is the org.opencontainers.image.ref.name annotation of the manifest
+//     in the index, it must be a reference limiting the tag to a repository. Bare tags are ignored
+//     as they would resolve the images of any repository to the manifests of the layout.
+//   - the signatures and attestations written by cosign save by their cosign tag, the tag is
+//     derived from the digest of the saved image so it is served for every repository
+//   - the referrers of the manifests, e.g. notary signatures
+type Layout struct {
+	files layoutFiles
+	// tags are the manifests of the index by repository and tag, and the cosign tags
+	tags map[string]v1.Descriptor
+	// manifests are the media types of the manifests of the layout by digest
+	manifests map[v1.Hash]types.MediaType
+	// referrers are the manifests of the layout by digest of their subject
+	referrers map[v1.Hash][]v1.Descriptor
+}
+
+// OpenLayout opens the OCI image layout directory or tarball
+func OpenLayout(layoutPath string) (*Layout, error) {
+	info, err := os.Stat(layoutPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCI layout: %w", err)
+	}
+	var files layoutFiles = dirFiles(layoutPath)
+	if !info.IsDir() {
+		if files, err = openTarFiles(layoutPath); err != nil {
+			return nil, err
+		}
+	}
+	b, err := files.ReadFile("index.json")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an OCI layout: %w", layoutPath, err)
+	}
+	index, err := v1.ParseIndexManifest(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse index of OCI layout %s: %w", layoutPath, err)
+	}
+	l := &Layout{
+		files:     files,
+		tags:      map[string]v1.Descriptor{},
+		manifests: map[v1.Hash]types.MediaType{},
+		referrers: map[v1.Hash][]v1.Descriptor{},
+	}
+	var image *v1.Descriptor
+	for i, desc := range index.Manifests {
+		if err := l.addManifest(desc, true); err != nil {
+			return nil, fmt.Errorf("invalid OCI layout %s: %w", layoutPath, err)
+		}
+		switch desc.Annotations[cosignKindAnnotation] {
+		case cosignImageKind, cosignImageIndexKind:
+			image = &index.Manifests[i]
+		case cosignSigsKind:
+			if image != nil {
+				l.tags[cosignTag(image.Digest, "sig")] = desc
+			}
+		case cosignAttsKind:
+			if image != nil {
+				l.tags[cosignTag(image.Digest, "att")] = desc
+			}
+		}
+		refName := desc.Annotations[refNameAnnotation]
+		if refName == "" || !strings.Contains(refName, "/") {
+			continue
+		}
+		ref, err := name.NewTag(refName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference %q in OCI layout %s: %w", refName, layoutPath, err)
+		}
+		l.tags[ref.Context().Name()+":"+ref.TagStr()] = desc
+	}
+	return l, nil
+}
+
+// cosignTag returns the tag of the signatures or the attestations of the image in the registry
+func cosignTag(digest v1.Hash, suffix string) string {
+	return fmt.Sprintf("%s-%s.%s", digest.Algorithm, digest.Hex, suffix)
+}
+
+// addManifest records the manifest and the manifests of the index, the manifests of an index that
+// are not part of the layout are ignored, e.g. the platforms that were not copied
+func (l *Layout) addManifest(desc v1.Descriptor, required bool) error {
+	if _, ok := l.manifests[desc.Digest]; ok {
+		return nil
+	}
+	b, err := l.readBlob(desc.Digest)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read manifest %s: %w", desc.Digest, err)
+	}
+	var manifest struct {
+		ArtifactType string            `json:"artifactType,omitempty"`
+		Config       v1.Descriptor     `json:"config"`
+		Manifests    []v1.Descriptor   `json:"manifests,omitempty"`
+		Subject      *v1.Descriptor    `json:"subject,omitempty"`
+		Annotations  map[string]string `json:"annotations,omitempty"`
+	}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest %s: %w", desc.Digest, err)
+	}
+	l.manifests[desc.Digest] = desc.MediaType
+	if manifest.Subject != nil {
+		artifactType := manifest.ArtifactType
+		if artifactType == "" {
+			artifactType = string(manifest.Config.MediaType)
+		}
+		l.referrers[manifest.Subject.Digest] = append(l.referrers[manifest.Subject.Digest], v1.Descriptor{
+			MediaType:    desc.MediaType,
+			Size:         desc.Size,
+			Digest:       desc.Digest,
+			Annotations:  manifest.Annotations,
+			ArtifactType: artifactType,
+		})
+	}
+	for _, child := range manifest.Manifests {
+		if child.MediaType.IsIndex() || child.MediaType.IsImage() {
+			if err := l.addManifest(child, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Layout) readBlob(digest v1.Hash) ([]byte, error) {
+	return l.files.ReadFile(path.Join("blobs", digest.Algorithm, digest.Hex))
+}
+
+// Client returns a registry client reading the layout, the layout needs no credentials and the
+// keychain is ignored
+func (l *Layout) Client(authn.Keychain) registryclient.Client {
+	return NewClient(WithTransport(l))
+}
+
+// RoundTrip serves the read only registry API from the layout
+func (l *Layout) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return registryError(req, http.StatusMethodNotAllowed, "UNSUPPORTED", "the OCI layout is read only"), nil
+	}
+	p := strings.TrimSuffix(req.URL.Path, "/")
+	if p == "/v2" {
+		return layoutResponse(req, http.StatusOK, "application/json", "", []byte("{}")), nil
+	}
+	repository, ok := strings.CutPrefix(p, "/v2/")
+	if !ok {
+		return registryError(req, http.StatusNotFound, "NAME_UNKNOWN", "unknown path "+req.URL.Path), nil
+	}
+	for _, route := range []struct {
+		path  string
+		serve func(*http.Request, string, string) *http.Response
+	}{
+		{path: "/manifests/", serve: l.serveManifest},
+		{path: "/blobs/", serve: l.serveBlob},
+		{path: "/referrers/", serve: l.serveReferrers},
+	} {
+		if i := strings.LastIndex(repository, route.path); i > 0 {
+			return route.serve(req, repository[:i], repository[i+len(route.path):]), nil
+		}
+	}
+	return registryError(req, http.StatusNotFound, "NAME_UNKNOWN", "unknown path "+req.URL.Path), nil
+}
+
+func (l *Layout) serveManifest(req *http.Request, repository, reference string) *http.Response {
+	var mediaType types.MediaType
+	digest, err := v1.NewHash(reference)
+	if err == nil {
+		mediaType = l.manifests[digest]
+	} else {
+		desc, ok := l.tag(req.URL.Host, repository, reference)
+		if !ok {
+			return registryError(req, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("tag %s not found in OCI layout", reference))
+		}
+		digest, mediaType = desc.Digest, desc.MediaType
+	}
+	b, err := l.readBlob(digest)
+	if err != nil {
+		return registryError(req, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("manifest %s not found in OCI layout", reference))
+	}
+	if mediaType == "" {
+		var manifest struct {
+			MediaType types.MediaType `json:"mediaType"`
+		}
+		if err := json.Unmarshal(b, &manifest); err != nil || manifest.MediaType == "" {
+			manifest.MediaType = types.OCIManifestSchema1
+		}
+		mediaType = manifest.MediaType
+	}
+	return layoutResponse(req, http.StatusOK, string(mediaType), digest.String(), b)
+}
+
+// tag returns the manifest of the tag of the repository or of the cosign tag
+func (l *Layout) tag(host, repository, tag string) (v1.Descriptor, bool) {
+	if repo, err := name.NewRepository(host + "/" + repository); err == nil {
+		if desc, ok := l.tags[repo.Name()+":"+tag]; ok {
+			return desc, true
+		}
+	}
+	desc, ok := l.tags[tag]
+	return desc, ok
+}
+
+func (l *Layout) serveBlob(req *http.Request, _, reference string) *http.Response {
+	digest, err := v1.NewHash(reference)
+	if err != nil {
+		return registryError(req, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+	}
+	b, err := l.readBlob(digest)
+	if err != nil {
+		return registryError(req, http.StatusNotFound, "BLOB_UNKNOWN", fmt.Sprintf("blob %s not found in OCI layout", reference))
+	}
+	return layoutResponse(req, http.StatusOK, "application/octet-stream", digest.String(), b)
+}
+
+func (l *Layout) serveReferrers(req *http.Request, _, reference string) *http.Response {
+	digest, err := v1.NewHash(reference)
+	if err != nil {
+		return registryError(req, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+	}
+	artifactType := req.URL.Query().Get("artifactType")
+	index := v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests:     []v1.Descriptor{},
+	}
+	for _, desc := range l.referrers[digest] {
+		if artifactType == "" || desc.ArtifactType == artifactType {
+			index.Manifests = append(index.Manifests, desc)
+		}
+	}
+	b, err := json.Marshal(index)
+	if err != nil {
+		return registryError(req, http.StatusInternalServerError, "UNKNOWN", err.Error())
+	}
+	resp := layoutResponse(req, http.StatusOK, string(types.OCIImageIndex), "", b)
+	if artifactType != "" {
+		resp.Header.Set("OCI-Filters-Applied", "artifactType")
+	}
+	return resp
+}
+
+// registryError returns the error response of the registry API
+func registryError(req *http.Request, status int, code, message string) *http.Response {
+	b, _ := json.Marshal(map[string]interface{}{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+	return layoutResponse(req, status, "application/json", "", b)
+}
+
+func layoutResponse(req *http.Request, status int, contentType, digest string, body []byte) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	if digest != "" {
+		header.Set("Docker-Content-Digest", digest)
+	}
+	if req.Method == http.MethodHead {
+		body = nil
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// layoutFiles reads the files of an OCI layout by slash separated path
+type layoutFiles interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// dirFiles reads the files of a layout directory
+type dirFiles string
+
+func (d dirFiles) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// tarFiles reads the files of a layout tarball, the files are located when the layout is opened
+// and read from the tarball when needed
+type tarFiles struct {
+	path    string
+	entries map[string]tarEntry
+}
+
+type tarEntry struct {
+	offset int64
+	size   int64
+}
+
+func openTarFiles(tarPath string) (*tarFiles, error) {
+	f, err := os.Open(filepath.Clean(tarPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCI layout: %w", err)
+	}
+	defer f.Close()
+	files := &tarFiles{path: tarPath, entries: map[string]tarEntry{}}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OCI layout tarball %s: %w", tarPath, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// the reader is positioned at the content of the file once its header is read
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OCI layout tarball %s: %w", tarPath, err)
+		}
+		files.entries[path.Clean(strings.TrimPrefix(hdr.Name, "./"))] = tarEntry{offset: offset, size: hdr.Size}
+	}
+}
+
+func (t *tarFiles) ReadFile(name string) ([]byte, error) {
+	entry, ok := t.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, err := os.Open(filepath.Clean(t.path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.NewSectionReader(f, entry.offset, entry.size))
+}
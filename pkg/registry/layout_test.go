@@ -0,0 +1,182 @@
+package registry
+
+import (
+	"archive/tar"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeLayout writes an OCI layout with an image tagged v2 for ghcr.io/org/app and with the bare
+// tag v1, and an artifact referring to the image. It returns the layout path and
+// the digests of the image and the artifact.
+func writeLayout(t *testing.T) (string, v1.Hash, v1.Hash) {
+	t.Helper()
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	assert.NoError(t, err)
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{refNameAnnotation: "v1"})))
+	assert.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{refNameAnnotation: "ghcr.io/org/app:v2"})))
+	desc, err := descriptor(img)
+	assert.NoError(t, err)
+	artifact, err := random.Image(128, 1)
+	assert.NoError(t, err)
+	artifact = mutate.ConfigMediaType(mutate.MediaType(artifact, types.OCIManifestSchema1), "application/vnd.example.signature")
+	artifact = mutate.Subject(artifact, desc).(v1.Image)
+	assert.NoError(t, p.AppendImage(artifact))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+	artifactDigest, err := artifact.Digest()
+	assert.NoError(t, err)
+	return dir, digest, artifactDigest
+}
+
+// descriptor returns the descriptor of the image
+func descriptor(img v1.Image) (v1.Descriptor, error) {
+	digest, err := img.Digest()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	size, err := img.Size()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+	return v1.Descriptor{MediaType: mediaType, Size: size, Digest: digest}, nil
+}
+
+// writeTar writes the files of the directory to a tarball and returns its path
+func writeTar(t *testing.T, dir string) string {
+	t.Helper()
+	tarPath := filepath.Join(t.TempDir(), "layout.tar")
+	f, err := os.Create(tarPath)
+	assert.NoError(t, err)
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	assert.NoError(t, tw.AddFS(os.DirFS(dir)))
+	assert.NoError(t, tw.Close())
+	return tarPath
+}
+
+func Test_Layout(t *testing.T) {
+	dir, digest, artifactDigest := writeLayout(t)
+	for kind, layoutPath := range map[string]string{"directory": dir, "tarball": writeTar(t, dir)} {
+		t.Run(kind, func(t *testing.T) {
+			l, err := OpenLayout(layoutPath)
+			assert.NoError(t, err)
+			client := l.Client(nil)
+
+			for _, image := range []string{"ghcr.io/org/app:v2", "ghcr.io/org/app@" + digest.String()} {
+				desc, err := client.FetchImageDescriptor(context.Background(), image)
+				assert.NoError(t, err, image)
+				assert.Equal(t, digest, desc.Digest, image)
+			}
+			// bare tags are not served, they would resolve the images of any repository
+			for _, image := range []string{"ghcr.io/other/app:v2", "ghcr.io/org/app:v1", "attacker.io/anything:v1"} {
+				_, err = client.FetchImageDescriptor(context.Background(), image)
+				assert.ErrorContains(t, err, "MANIFEST_UNKNOWN", image)
+			}
+
+			opts, err := client.Options(context.Background())
+			assert.NoError(t, err)
+			ref, err := name.NewDigest("ghcr.io/org/app@" + digest.String())
+			assert.NoError(t, err)
+			referrers, err := remote.Referrers(ref, opts...)
+			assert.NoError(t, err)
+			index, err := referrers.IndexManifest()
+			assert.NoError(t, err)
+			assert.Len(t, index.Manifests, 1)
+			assert.Equal(t, artifactDigest, index.Manifests[0].Digest)
+			assert.Equal(t, "application/vnd.example.signature", index.Manifests[0].ArtifactType)
+
+			referrers, err = remote.Referrers(ref, append(opts, remote.WithFilter("artifactType", "application/vnd.example.other"))...)
+			assert.NoError(t, err)
+			index, err = referrers.IndexManifest()
+			assert.NoError(t, err)
+			assert.Empty(t, index.Manifests)
+
+			img, err := remote.Image(ref, opts...)
+			assert.NoError(t, err)
+			layers, err := img.Layers()
+			assert.NoError(t, err)
+			_, err = layers[0].Compressed()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_Layout_CosignSave(t *testing.T) {
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	assert.NoError(t, err)
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	sigs, err := random.Image(128, 1)
+	assert.NoError(t, err)
+	atts, err := random.Image(128, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{cosignKindAnnotation: cosignImageKind})))
+	assert.NoError(t, p.AppendImage(sigs, layout.WithAnnotations(map[string]string{cosignKindAnnotation: cosignSigsKind})))
+	assert.NoError(t, p.AppendImage(atts, layout.WithAnnotations(map[string]string{cosignKindAnnotation: cosignAttsKind})))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+
+	l, err := OpenLayout(dir)
+	assert.NoError(t, err)
+	for suffix, want := range map[string]v1.Image{"sig": sigs, "att": atts} {
+		wantDigest, err := want.Digest()
+		assert.NoError(t, err)
+		desc, err := l.Client(nil).FetchImageDescriptor(context.Background(), "ghcr.io/org/app:"+cosignTag(digest, suffix))
+		assert.NoError(t, err)
+		assert.Equal(t, wantDigest, desc.Digest)
+	}
+}
+
+func Test_Layout_ReadOnly(t *testing.T) {
+	dir, _, _ := writeLayout(t)
+	l, err := OpenLayout(dir)
+	assert.NoError(t, err)
+	img, err := random.Image(128, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference("ghcr.io/org/app:v3")
+	assert.NoError(t, err)
+	err = remote.Write(ref, img, remote.WithTransport(l))
+	assert.ErrorContains(t, err, "the OCI layout is read only")
+}
+
+func Test_OpenLayout_Invalid(t *testing.T) {
+	_, err := OpenLayout(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "failed to open OCI layout")
+
+	dir := t.TempDir()
+	_, err = OpenLayout(dir)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.ErrorContains(t, err, "is not an OCI layout")
+
+	notTar := filepath.Join(dir, "layout.tar")
+	assert.NoError(t, os.WriteFile(notTar, []byte("not a tarball"), 0o600))
+	_, err = OpenLayout(notTar)
+	assert.ErrorContains(t, err, "failed to read OCI layout tarball")
+
+	dir, digest, _ := writeLayout(t)
+	assert.NoError(t, os.Remove(filepath.Join(dir, "blobs", digest.Algorithm, digest.Hex)))
+	_, err = OpenLayout(dir)
+	assert.ErrorContains(t, err, "failed to read manifest "+digest.String())
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// Mirror rewrites the image references of a registry or a repository to a mirror. A prefix
+// ending with * rewrites every image reference starting with the prefix, e.g. docker.io/* to
+// mirror.corp/dockerhub/*, otherwise only the images of the repository are rewritten and keep
+// their tag and digest.
+type Mirror struct {
+	// Prefix is the prefix of the normalized image references rewritten by the mirror
+	Prefix string `json:"prefix"`
+	// Mirror replaces the prefix in the rewritten image references
+	Mirror string `json:"mirror"`
+}
+
+// Mirrors rewrites image references with the first matching mirror
+type Mirrors []Mirror
+
+// Validate checks the prefixes and the mirrors are wildcards or repositories alike
+func (m Mirrors) Validate() error {
+	for _, mirror := range m {
+		if mirror.Prefix == "" || mirror.Mirror == "" {
+			return errors.New("mirror requires a prefix and a mirror")
+		}
+		prefix, mirrorPrefix := strings.HasSuffix(mirror.Prefix, "*"), strings.HasSuffix(mirror.Mirror, "*")
+		if prefix != mirrorPrefix {
+			return fmt.Errorf("prefix %s and mirror %s must both or neither end with *", mirror.Prefix, mirror.Mirror)
+		}
+		if prefix {
+			continue
+		}
+		for _, repository := range []string{mirror.Prefix, mirror.Mirror} {
+			if _, err := name.NewRepository(repository); err != nil {
+				return fmt.Errorf("invalid repository %q: %w", repository, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Rewrite returns the image reference of the mirror of the image, the image is returned unchanged
+// when no mirror matches
+func (m Mirrors) Rewrite(image string) string {
+	for _, mirror := range m {
+		if rewritten, ok := mirror.rewrite(image); ok {
+			return rewritten
+		}
+	}
+	return image
+}
+
+func (m Mirror) rewrite(image string) (string, bool) {
+	if prefix, ok := strings.CutSuffix(m.Prefix, "*"); ok {
+		if !strings.HasPrefix(image, prefix) {
+			return "", false
+		}
+		return strings.TrimSuffix(m.Mirror, "*") + image[len(prefix):], true
+	}
+	repository, identifier := splitRepository(image)
+	if repository != m.Prefix {
+		return "", false
+	}
+	return m.Mirror + identifier, true
+}
+
+// splitRepository splits the image reference into its repository and its tag and digest
+func splitRepository(image string) (string, string) {
+	end := len(image)
+	if i := strings.Index(image, "@"); i >= 0 {
+		end = i
+	}
+	if i := strings.LastIndex(image[:end], ":"); i > strings.LastIndex(image[:end], "/") {
+		end = i
+	}
+	return image[:end], image[end:]
+}
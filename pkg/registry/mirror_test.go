@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Mirrors_Rewrite(t *testing.T) {
+	mirrors := Mirrors{
+		{Prefix: "docker.io/library/nginx", Mirror: "mirror.corp/nginx"},
+		{Prefix: "docker.io/*", Mirror: "mirror.corp/dockerhub/*"},
+		{Prefix: "ghcr.io/nirmata/*", Mirror: "localhost:5000/*"},
+	}
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "docker.io/library/nginx:1.25", want: "mirror.corp/nginx:1.25"},
+		{image: "docker.io/library/nginx@sha256:aaaa", want: "mirror.corp/nginx@sha256:aaaa"},
+		{image: "docker.io/library/nginx:1.25@sha256:aaaa", want: "mirror.corp/nginx:1.25@sha256:aaaa"},
+		{image: "docker.io/library/nginx-unprivileged:1.25", want: "mirror.corp/dockerhub/library/nginx-unprivileged:1.25"},
+		{image: "docker.io/library/busybox:latest", want: "mirror.corp/dockerhub/library/busybox:latest"},
+		{image: "ghcr.io/nirmata/app:v1", want: "localhost:5000/app:v1"},
+		{image: "ghcr.io/other/app:v1", want: "ghcr.io/other/app:v1"},
+		{image: "localhost:5000/app:v1", want: "localhost:5000/app:v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, mirrors.Rewrite(tt.image))
+		})
+	}
+	assert.Equal(t, "docker.io/library/nginx:1.25", Mirrors(nil).Rewrite("docker.io/library/nginx:1.25"))
+}
+
+func Test_Mirrors_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirrors Mirrors
+		wantErr string
+	}{{
+		name:    "valid",
+		mirrors: Mirrors{{Prefix: "docker.io/*", Mirror: "mirror.corp/dockerhub/*"}, {Prefix: "ghcr.io/nirmata/app", Mirror: "mirror.corp/app"}},
+	}, {
+		name:    "missing mirror",
+		mirrors: Mirrors{{Prefix: "docker.io/*"}},
+		wantErr: "mirror requires a prefix and a mirror",
+	}, {
+		name:    "wildcard prefix",
+		mirrors: Mirrors{{Prefix: "docker.io/*", Mirror: "mirror.corp/dockerhub"}},
+		wantErr: "prefix docker.io/* and mirror mirror.corp/dockerhub must both or neither end with *",
+	}, {
+		name:    "wildcard mirror",
+		mirrors: Mirrors{{Prefix: "docker.io/library/nginx", Mirror: "mirror.corp/*"}},
+		wantErr: "prefix docker.io/library/nginx and mirror mirror.corp/* must both or neither end with *",
+	}, {
+		name:    "invalid repository",
+		mirrors: Mirrors{{Prefix: "ghcr.io/nirmata/app:v1", Mirror: "mirror.corp/app"}},
+		wantErr: `invalid repository "ghcr.io/nirmata/app:v1"`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mirrors.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Registry configures the connections to a registry
+type Registry struct {
+	// Registry is the host of the registry, e.g. harbor.example.com or localhost:5000
+	Registry string `json:"registry"`
+	// CAFile is the path of a PEM bundle of the certificate authorities trusted in addition to the
+	// system roots
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile and KeyFile are the paths of the PEM client certificate and key presented to the registry
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// Insecure accesses the registry with plain HTTP
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// Transport configures the connections to the registries. The registries that are not configured
+// are accessed with HTTPS, trusting the system roots.
+type Transport struct {
+	// Registries are the connection settings by registry
+	Registries []Registry
+	// Proxy is the URL of the HTTP(S) proxy used to access the registries, the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables are used when empty
+	Proxy string
+	// NoProxy is a comma separated list of hosts, domains and CIDRs accessed without the proxy
+	NoProxy string
+}
+
+// RoundTripper returns the transport of the registry client configured by t
+func (t Transport) RoundTripper() (http.RoundTripper, error) {
+	proxy := http.ProxyFromEnvironment
+	if t.Proxy != "" {
+		if _, err := url.Parse(t.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", t.Proxy, err)
+		}
+		config := &httpproxy.Config{HTTPProxy: t.Proxy, HTTPSProxy: t.Proxy, NoProxy: t.NoProxy}
+		proxyFunc := config.ProxyFunc()
+		proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+	base := defaultTransport()
+	base.Proxy = proxy
+	rt := &registryTransport{
+		base:       base,
+		registries: map[string]http.RoundTripper{},
+		insecure:   map[string]bool{},
+	}
+	for _, r := range t.Registries {
+		registry, err := name.NewRegistry(r.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry %q: %w", r.Registry, err)
+		}
+		host := registry.RegistryStr()
+		if r.Insecure {
+			rt.insecure[host] = true
+		}
+		if r.CAFile == "" && r.CertFile == "" && r.KeyFile == "" {
+			continue
+		}
+		tlsConfig, err := r.tlsConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration of registry %s: %w", r.Registry, err)
+		}
+		transport := defaultTransport()
+		transport.Proxy = proxy
+		transport.TLSClientConfig = tlsConfig
+		rt.registries[host] = transport
+	}
+	return rt, nil
+}
+
+// tlsConfig returns the TLS configuration of the registry
+func (r Registry) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r.CAFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(r.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA bundle %s", r.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if r.CertFile != "" || r.KeyFile != "" {
+		if r.CertFile == "" || r.KeyFile == "" {
+			return nil, errors.New("client certificate requires both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// registryTransport routes the requests to the transport of their registry, the requests to the
+// insecure registries are sent with plain HTTP
+type registryTransport struct {
+	base       http.RoundTripper
+	registries map[string]http.RoundTripper
+	insecure   map[string]bool
+}
+
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.insecure[req.URL.Host] && req.URL.Scheme == "https" {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = "http"
+	}
+	if transport, ok := t.registries[req.URL.Host]; ok {
+		return transport.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+// pushImage pushes a random image to the registry of the server and returns its digest
+func pushImage(t *testing.T, srv *httptest.Server, image string) v1.Hash {
+	t.Helper()
+	img, err := random.Image(1024, 1)
+	assert.NoError(t, err)
+	ref, err := name.ParseReference(image)
+	assert.NoError(t, err)
+	assert.NoError(t, remote.Write(ref, img, remote.WithTransport(srv.Client().Transport)))
+	digest, err := img.Digest()
+	assert.NoError(t, err)
+	return digest
+}
+
+// writePEM writes the PEM block to a file of the directory and returns its path
+func writePEM(t *testing.T, dir, file, blockType string, bytes []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, file)
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes}), 0o600))
+	return path
+}
+
+func Test_Transport_CAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(registry.New())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+	digest := pushImage(t, srv, host+"/test/app:v1")
+	caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	rt, err := Transport{}.RoundTripper()
+	assert.NoError(t, err)
+	_, err = NewClient(WithTransport(rt)).FetchImageDescriptor(context.Background(), host+"/test/app:v1")
+	assert.Error(t, err)
+
+	rt, err = Transport{Registries: []Registry{{Registry: host, CAFile: caFile}}}.RoundTripper()
+	assert.NoError(t, err)
+	desc, err := NewClient(WithTransport(rt)).FetchImageDescriptor(context.Background(), host+"/test/app:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, digest, desc.Digest)
+}
+
+func Test_Transport_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "verifier"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	certFile := writePEM(t, dir, "client.pem", "CERTIFICATE", cert)
+	keyFile := writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyBytes)
+	parsed, err := x509.ParseCertificate(cert)
+	assert.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(parsed)
+
+	// the image is pushed through a plain server sharing the registry with the server requiring
+	// client certificates
+	handler := registry.New()
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	digest := pushImage(t, plain, strings.TrimPrefix(plain.URL, "http://")+"/test/app:v1")
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+	caFile := writePEM(t, dir, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	rt, err := Transport{Registries: []Registry{{Registry: host, CAFile: caFile}}}.RoundTripper()
+	assert.NoError(t, err)
+	_, err = NewClient(WithTransport(rt)).FetchImageDescriptor(context.Background(), host+"/test/app:v1")
+	assert.Error(t, err)
+
+	rt, err = Transport{Registries: []Registry{{Registry: host, CAFile: caFile, CertFile: certFile, KeyFile: keyFile}}}.RoundTripper()
+	assert.NoError(t, err)
+	desc, err := NewClient(WithTransport(rt)).FetchImageDescriptor(context.Background(), host+"/test/app:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, digest, desc.Digest)
+}
+
+func Test_Transport_Insecure(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	pushImage(t, srv, host+"/test/app:v1")
+
+	rt, err := Transport{Registries: []Registry{{Registry: host, Insecure: true}}}.RoundTripper()
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "https://"+host+"/v2/", nil)
+	assert.NoError(t, err)
+	resp, err := rt.RoundTrip(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "https", req.URL.Scheme)
+}
+
+func Test_Transport_Proxy(t *testing.T) {
+	proxied := false
+	handler := registry.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the requests sent through a proxy have an absolute URL
+		proxied = proxied || r.URL.Host == "registry.example.com"
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	digest := pushImage(t, srv, strings.TrimPrefix(srv.URL, "http://")+"/test/app:v1")
+
+	rt, err := Transport{Registries: []Registry{{Registry: "registry.example.com", Insecure: true}}, Proxy: srv.URL}.RoundTripper()
+	assert.NoError(t, err)
+	desc, err := NewClient(WithTransport(rt)).FetchImageDescriptor(context.Background(), "registry.example.com/test/app:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, digest, desc.Digest)
+	assert.True(t, proxied)
+}
+
+func Test_Transport_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	invalidCA := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, os.WriteFile(invalidCA, []byte("not a certificate"), 0o600))
+
+	tests := []struct {
+		name     string
+		registry Registry
+		wantErr  string
+	}{{
+		name:     "invalid registry",
+		registry: Registry{Registry: "Registry.Example.com/app"},
+		wantErr:  `invalid registry "Registry.Example.com/app"`,
+	}, {
+		name:     "missing CA bundle",
+		registry: Registry{Registry: "harbor.example.com", CAFile: filepath.Join(dir, "missing.pem")},
+		wantErr:  "invalid TLS configuration of registry harbor.example.com: failed to read CA bundle",
+	}, {
+		name:     "invalid CA bundle",
+		registry: Registry{Registry: "harbor.example.com", CAFile: invalidCA},
+		wantErr:  "no certificate found in CA bundle",
+	}, {
+		name:     "key without certificate",
+		registry: Registry{Registry: "harbor.example.com", KeyFile: invalidCA},
+		wantErr:  "client certificate requires both a certificate and a key file",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Transport{Registries: []Registry{tt.registry}}.RoundTripper()
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
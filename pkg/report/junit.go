@@ -0,0 +1,129 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// newJUnit converts the engine response to JUnit XML test suites, every policy is a test suite
+// with one test case per image verified by its rules, or one test case for a rule without images
+func newJUnit(response imageverifier.Response) junitTestSuites {
+	suites := junitTestSuites{
+		Name: toolName,
+	}
+	var total time.Duration
+	for _, p := range response.PolicyResponses {
+		suite := junitTestSuite{
+			Name: p.Policy.Name,
+		}
+		var elapsed time.Duration
+		for _, r := range p.RuleResponses {
+			elapsed += r.Duration
+			className := p.Policy.Name + "." + r.Rule.Name
+			if r.Error != nil || len(r.VerificationResults) == 0 {
+				testCase := junitTestCase{
+					Name:      r.Rule.Name,
+					ClassName: className,
+					Time:      seconds(r.Duration),
+				}
+				switch r.VerificationOutcome {
+				case imageverifier.ERROR:
+					testCase.Error = &junitProblem{Message: errorString(r.Error), Type: string(r.Reason), Text: errorString(r.Error)}
+				case imageverifier.SKIP:
+					testCase.Skipped = &junitSkipped{Message: "rule has no image to verify"}
+				}
+				suite.add(testCase)
+				continue
+			}
+			for _, result := range r.VerificationResults {
+				testCase := junitTestCase{
+					Name:      r.Rule.Name + "/" + result.Image,
+					ClassName: className,
+					Time:      seconds(result.Duration),
+				}
+				switch result.VerificationOutcome {
+				case imageverifier.FAIL:
+					text := strings.Join(failures(result), "\n")
+					testCase.Failure = &junitProblem{Message: fmt.Sprintf("image %s failed verification", result.Image), Type: string(imageverifier.FAIL), Text: text}
+				case imageverifier.ERROR:
+					testCase.Error = &junitProblem{Message: errorString(result.Error), Type: string(result.Reason), Text: errorString(result.Error)}
+				case imageverifier.SKIP:
+					testCase.Skipped = &junitSkipped{Message: "no verification rule matches the image"}
+				case imageverifier.EXCEPTED:
+					testCase.Skipped = &junitSkipped{Message: fmt.Sprintf("failures waived by policy exception %s", result.Exception)}
+				}
+				suite.add(testCase)
+			}
+		}
+		suite.Time = seconds(elapsed)
+		total += elapsed
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = seconds(total)
+	return suites
+}
+
+func (s *junitTestSuite) add(testCase junitTestCase) {
+	s.Tests++
+	switch {
+	case testCase.Failure != nil:
+		s.Failures++
+	case testCase.Error != nil:
+		s.Errors++
+	case testCase.Skipped != nil:
+		s.Skipped++
+	}
+	s.TestCases = append(s.TestCases, testCase)
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
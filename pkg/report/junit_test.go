@@ -0,0 +1,31 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JUnit(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, JUnit, testResponse()))
+	assert.True(t, bytes.HasPrefix(out.Bytes(), []byte(xml.Header)))
+	var got junitTestSuites
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, 2, got.Tests)
+	assert.Equal(t, 1, got.Failures)
+	assert.Equal(t, 1, got.Skipped)
+	assert.Equal(t, "1.500", got.Time)
+	assert.Len(t, got.Suites, 1)
+	suite := got.Suites[0]
+	assert.Equal(t, "check-images", suite.Name)
+	assert.Len(t, suite.TestCases, 2)
+	assert.Equal(t, "signed/ghcr.io/nirmata/app:v1", suite.TestCases[0].Name)
+	assert.Equal(t, "check-images.signed", suite.TestCases[0].ClassName)
+	assert.Equal(t, "1.000", suite.TestCases[0].Time)
+	assert.Equal(t, "no signatures found", suite.TestCases[0].Failure.Text)
+	assert.Equal(t, "unmatched", suite.TestCases[1].Name)
+	assert.NotNil(t, suite.TestCases[1].Skipped)
+}
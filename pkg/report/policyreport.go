@@ -0,0 +1,214 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	policyreportv1alpha2 "github.com/kyverno/kyverno/api/policyreport/v1alpha2"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// annotationCategory and annotationSeverity are the policy annotations used by Kyverno to set
+	// the category and the severity of the report results
+	annotationCategory = "policies.kyverno.io/category"
+	annotationSeverity = "policies.kyverno.io/severity"
+)
+
+// invalidNameCharacters matches the characters not allowed in the name of a report
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// NewPolicyReport converts the engine response to a wg-policy PolicyReport in the namespace of the
+// verified resource. The resource is the scope of the report and of every result.
+func NewPolicyReport(response imageverifier.Response, opts ...Option) *policyreportv1alpha2.PolicyReport {
+	o := newOptions(opts)
+	scope := resourceReference(response.Resource, o)
+	results, summary := policyReportResults(response, scope, o)
+	return &policyreportv1alpha2.PolicyReport{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policyreportv1alpha2.SchemeGroupVersion.String(),
+			Kind:       "PolicyReport",
+		},
+		ObjectMeta: reportMeta(scope),
+		Scope:      &scope,
+		Summary:    summary,
+		Results:    results,
+	}
+}
+
+// NewClusterPolicyReport converts the engine response to a wg-policy ClusterPolicyReport, it is used
+// for resources without namespace such as ECS task definitions
+func NewClusterPolicyReport(response imageverifier.Response, opts ...Option) *policyreportv1alpha2.ClusterPolicyReport {
+	o := newOptions(opts)
+	scope := resourceReference(response.Resource, o)
+	results, summary := policyReportResults(response, scope, o)
+	meta := reportMeta(scope)
+	meta.Namespace = ""
+	return &policyreportv1alpha2.ClusterPolicyReport{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policyreportv1alpha2.SchemeGroupVersion.String(),
+			Kind:       "ClusterPolicyReport",
+		},
+		ObjectMeta: meta,
+		Scope:      &scope,
+		Summary:    summary,
+		Results:    results,
+	}
+}
+
+// policyReportResults returns one result per rule of every policy and their summary
+func policyReportResults(response imageverifier.Response, scope corev1.ObjectReference, o options) ([]policyreportv1alpha2.PolicyReportResult, policyreportv1alpha2.PolicyReportSummary) {
+	timestamp := metav1.Timestamp{Seconds: o.timestamp.Unix(), Nanos: int32(o.timestamp.Nanosecond())}
+	var summary policyreportv1alpha2.PolicyReportSummary
+	var results []policyreportv1alpha2.PolicyReportResult
+	for _, p := range response.PolicyResponses {
+		for _, r := range p.RuleResponses {
+			result := policyreportv1alpha2.PolicyReportResult{
+				Source:    toolName,
+				Policy:    p.Policy.Name,
+				Rule:      r.Rule.Name,
+				Resources: []corev1.ObjectReference{scope},
+				Message:   ruleMessage(r),
+				Result:    policyResult(r.VerificationOutcome),
+				Scored:    true,
+				Timestamp: timestamp,
+				Category:  p.Policy.Annotations[annotationCategory],
+				Severity:  policyreportv1alpha2.PolicySeverity(p.Policy.Annotations[annotationSeverity]),
+			}
+			if r.ValidationFailureAction != "" {
+				result.Properties = map[string]string{"validationFailureAction": string(r.ValidationFailureAction)}
+			}
+			if exceptions := ruleExceptions(r); exceptions != "" {
+				if result.Properties == nil {
+					result.Properties = map[string]string{}
+				}
+				result.Properties["exception"] = exceptions
+			}
+			switch result.Result {
+			case policyreportv1alpha2.StatusPass:
+				summary.Pass++
+			case policyreportv1alpha2.StatusFail:
+				summary.Fail++
+			case policyreportv1alpha2.StatusSkip:
+				summary.Skip++
+			case policyreportv1alpha2.StatusError:
+				summary.Error++
+			}
+			results = append(results, result)
+		}
+	}
+	return results, summary
+}
+
+func policyResult(outcome imageverifier.VerificationOutcome) policyreportv1alpha2.PolicyResult {
+	switch outcome {
+	case imageverifier.PASS:
+		return policyreportv1alpha2.StatusPass
+	case imageverifier.FAIL:
+		return policyreportv1alpha2.StatusFail
+	case imageverifier.ERROR:
+		return policyreportv1alpha2.StatusError
+	default:
+		return policyreportv1alpha2.StatusSkip
+	}
+}
+
+// ruleMessage describes the outcome of every image verified by the rule
+func ruleMessage(r imageverifier.RuleResponse) string {
+	if r.Error != nil {
+		return fmt.Sprintf("rule could not be evaluated: %v", r.Error)
+	}
+	if len(r.VerificationResults) == 0 {
+		return "no image matched the rule"
+	}
+	messages := make([]string, 0, len(r.VerificationResults))
+	for _, result := range r.VerificationResults {
+		message := fmt.Sprintf("image %s %s", result.Image, result.VerificationOutcome)
+		switch result.VerificationOutcome {
+		case imageverifier.FAIL:
+			message += ": " + strings.Join(failures(result), "; ")
+		case imageverifier.ERROR:
+			message += fmt.Sprintf(": %v", result.Error)
+		case imageverifier.EXCEPTED:
+			message += ": waived by policy exception " + result.Exception
+		}
+		messages = append(messages, message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+// ruleExceptions returns the comma separated names of the policy exceptions applied to the rule
+func ruleExceptions(r imageverifier.RuleResponse) string {
+	var names []string
+	seen := map[string]bool{}
+	for _, result := range r.VerificationResults {
+		if result.Exception != "" && !seen[result.Exception] {
+			seen[result.Exception] = true
+			names = append(names, result.Exception)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// resourceReference identifies the verified resource. Kubernetes objects are identified by their
+// type and metadata, ECS task definitions by their family, and other resources by the name of the
+// resource file.
+func resourceReference(resource interface{}, o options) corev1.ObjectReference {
+	var ref corev1.ObjectReference
+	object, _ := resource.(map[string]interface{})
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		ref.APIVersion, _ = object["apiVersion"].(string)
+		ref.Kind, _ = object["kind"].(string)
+		ref.Name, _ = metadata["name"].(string)
+		ref.Namespace, _ = metadata["namespace"].(string)
+		uid, _ := metadata["uid"].(string)
+		ref.UID = types.UID(uid)
+		return ref
+	}
+	if family, ok := object["family"].(string); ok {
+		ref.Kind = "TaskDefinition"
+		ref.Name = family
+		return ref
+	}
+	if o.resourcePath != "" {
+		ref.Name = strings.TrimSuffix(filepath.Base(o.resourcePath), filepath.Ext(o.resourcePath))
+	}
+	return ref
+}
+
+// reportMeta returns the metadata of the report of the resource
+func reportMeta(scope corev1.ObjectReference) metav1.ObjectMeta {
+	name := "image-verification"
+	if scope.Name != "" {
+		name += "-" + strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(scope.Name), "-"), "-.")
+	}
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: scope.Namespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": toolName,
+		},
+	}
+}
+
+// isNamespaced returns true when the resource of the response has a namespace
+func isNamespaced(response imageverifier.Response, o options) bool {
+	return resourceReference(response.Resource, o).Namespace != ""
+}
+
+func newOptions(opts []Option) options {
+	o := options{timestamp: time.Now()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
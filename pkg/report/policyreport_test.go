@@ -0,0 +1,99 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	policyreportv1alpha2 "github.com/kyverno/kyverno/api/policyreport/v1alpha2"
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func Test_ClusterPolicyReport(t *testing.T) {
+	response := testResponse()
+	response.Resource = map[string]interface{}{"family": "Sample_App"}
+	response.PolicyResponses[0].Policy.Annotations = map[string]string{annotationSeverity: "high", annotationCategory: "Supply Chain"}
+	response.PolicyResponses[0].RuleResponses[0].ValidationFailureAction = v1alpha1.Audit
+	now := time.Unix(1700000000, 0)
+
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, PolicyReport, response, WithTimestamp(now)))
+	var got policyreportv1alpha2.ClusterPolicyReport
+	assert.NoError(t, yaml.Unmarshal(out.Bytes(), &got))
+
+	scope := corev1.ObjectReference{Kind: "TaskDefinition", Name: "Sample_App"}
+	assert.Equal(t, "ClusterPolicyReport", got.Kind)
+	assert.Equal(t, "wgpolicyk8s.io/v1alpha2", got.APIVersion)
+	assert.Equal(t, "image-verification-sample-app", got.Name)
+	assert.Equal(t, &scope, got.Scope)
+	assert.Equal(t, policyreportv1alpha2.PolicyReportSummary{Fail: 1, Skip: 1}, got.Summary)
+	assert.Equal(t, []policyreportv1alpha2.PolicyReportResult{{
+		Source:     "json-image-verification",
+		Policy:     "check-images",
+		Rule:       "signed",
+		Resources:  []corev1.ObjectReference{scope},
+		Message:    "image ghcr.io/nirmata/app:v1 FAIL: no signatures found",
+		Result:     policyreportv1alpha2.StatusFail,
+		Scored:     true,
+		Properties: map[string]string{"validationFailureAction": "Audit"},
+		Timestamp:  metav1.Timestamp{Seconds: now.Unix()},
+		Category:   "Supply Chain",
+		Severity:   policyreportv1alpha2.SeverityHigh,
+	}, {
+		Source:    "json-image-verification",
+		Policy:    "check-images",
+		Rule:      "unmatched",
+		Resources: []corev1.ObjectReference{scope},
+		Message:   "no image matched the rule",
+		Result:    policyreportv1alpha2.StatusSkip,
+		Scored:    true,
+		Timestamp: metav1.Timestamp{Seconds: now.Unix()},
+		Category:  "Supply Chain",
+		Severity:  policyreportv1alpha2.SeverityHigh,
+	}}, got.Results)
+}
+
+func Test_PolicyReport(t *testing.T) {
+	response := testResponse()
+	response.Resource = map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "app", "namespace": "prod", "uid": "1234"},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, PolicyReport, response))
+	var got policyreportv1alpha2.PolicyReport
+	assert.NoError(t, yaml.Unmarshal(out.Bytes(), &got))
+
+	assert.Equal(t, "PolicyReport", got.Kind)
+	assert.Equal(t, "image-verification-app", got.Name)
+	assert.Equal(t, "prod", got.Namespace)
+	assert.Equal(t, &corev1.ObjectReference{APIVersion: "v1", Kind: "Pod", Name: "app", Namespace: "prod", UID: "1234"}, got.Scope)
+	assert.Len(t, got.Results, 2)
+	assert.NotZero(t, got.Results[0].Timestamp.Seconds)
+}
+
+func Test_PolicyReport_Excepted(t *testing.T) {
+	response := testResponse()
+	rule := &response.PolicyResponses[0].RuleResponses[0]
+	rule.VerificationOutcome = imageverifier.EXCEPTED
+	rule.VerificationResults[0].VerificationOutcome = imageverifier.EXCEPTED
+	rule.VerificationResults[0].Exception = "legacy-images"
+
+	report := NewClusterPolicyReport(response)
+	assert.Equal(t, policyreportv1alpha2.PolicyReportSummary{Skip: 2}, report.Summary)
+	assert.Equal(t, policyreportv1alpha2.StatusSkip, report.Results[0].Result)
+	assert.Equal(t, "image ghcr.io/nirmata/app:v1 EXCEPTED: waived by policy exception legacy-images", report.Results[0].Message)
+	assert.Equal(t, map[string]string{"exception": "legacy-images"}, report.Results[0].Properties)
+}
+
+func Test_ResourceReference_Path(t *testing.T) {
+	ref := resourceReference(map[string]interface{}{"containerDefinitions": []interface{}{}}, options{resourcePath: "examples/payload.json"})
+	assert.Equal(t, corev1.ObjectReference{Name: "payload"}, ref)
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+)
+
+// SchemaVersion is the version of the report schema, it is bumped on any incompatible change
+const SchemaVersion = "v1"
+
+// Report is the serializable representation of an engine response, its schema is stable and
+// documented in the README
+type Report struct {
+	SchemaVersion string                         `json:"schemaVersion"`
+	Summary       Summary                        `json:"summary"`
+	Policies      []Policy                       `json:"policies"`
+	Patches       []imageverifier.PatchOperation `json:"patches,omitempty"`
+}
+
+// Summary counts the rule outcomes of all policies
+type Summary struct {
+	Pass     int `json:"pass"`
+	Fail     int `json:"fail"`
+	Skip     int `json:"skip"`
+	Error    int `json:"error"`
+	Excepted int `json:"excepted"`
+}
+
+// Policy is the result of a policy
+type Policy struct {
+	Name                    string                           `json:"name"`
+	ValidationFailureAction v1alpha1.ValidationFailureAction `json:"validationFailureAction"`
+	Rules                   []Rule                           `json:"rules"`
+}
+
+// Rule is the result of a rule, the error is only set when the rule could not be evaluated. The
+// failures of rules in Audit mode are reported without blocking.
+type Rule struct {
+	Name                    string                            `json:"name"`
+	ValidationFailureAction v1alpha1.ValidationFailureAction  `json:"validationFailureAction"`
+	Outcome                 imageverifier.VerificationOutcome `json:"outcome"`
+	Error                   string                            `json:"error,omitempty"`
+	Reason                  imageverifier.ErrorReason         `json:"reason,omitempty"`
+	Duration                Duration                          `json:"duration"`
+	Images                  []Image                           `json:"images,omitempty"`
+}
+
+// Image is the result of an image extracted by a rule
+type Image struct {
+	Key               string                            `json:"key"`
+	Pointer           string                            `json:"pointer,omitempty"`
+	Image             string                            `json:"image"`
+	Outcome           imageverifier.VerificationOutcome `json:"outcome"`
+	Error             string                            `json:"error,omitempty"`
+	Reason            imageverifier.ErrorReason         `json:"reason,omitempty"`
+	Exception         string                            `json:"exception,omitempty"`
+	Digest            string                            `json:"digest,omitempty"`
+	Cache             imageverifier.CacheStatus         `json:"cache,omitempty"`
+	Mirror            string                            `json:"mirror,omitempty"`
+	MutatedImage      string                            `json:"mutatedImage,omitempty"`
+	Duration          Duration                          `json:"duration"`
+	VerificationRules []VerificationRule                `json:"verificationRules,omitempty"`
+}
+
+// VerificationRule is the result of a verification rule matching an image, index is the position
+// of the verification rule in the rule
+type VerificationRule struct {
+	Index           int      `json:"index"`
+	ImageReferences []string `json:"imageReferences"`
+	Failures        []string `json:"failures,omitempty"`
+	Cached          bool     `json:"cached,omitempty"`
+	Duration        Duration `json:"duration"`
+}
+
+// Duration is serialized as a Go duration string, e.g. 1.5s or 250ms
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// New converts the engine response to a report
+func New(response imageverifier.Response) Report {
+	report := Report{
+		SchemaVersion: SchemaVersion,
+		Policies:      make([]Policy, 0, len(response.PolicyResponses)),
+		Patches:       response.Patches,
+	}
+	for _, p := range response.PolicyResponses {
+		policy := Policy{
+			Name:                    p.Policy.Name,
+			ValidationFailureAction: p.ValidationFailureAction,
+			Rules:                   make([]Rule, 0, len(p.RuleResponses)),
+		}
+		for _, r := range p.RuleResponses {
+			report.Summary.add(r.VerificationOutcome)
+			rule := Rule{
+				Name:                    r.Rule.Name,
+				ValidationFailureAction: r.ValidationFailureAction,
+				Outcome:                 r.VerificationOutcome,
+				Error:                   errorString(r.Error),
+				Reason:                  r.Reason,
+				Duration:                Duration(r.Duration),
+			}
+			for _, result := range r.VerificationResults {
+				rule.Images = append(rule.Images, newImage(result))
+			}
+			policy.Rules = append(policy.Rules, rule)
+		}
+		report.Policies = append(report.Policies, policy)
+	}
+	return report
+}
+
+func newImage(result imageverifier.VerificationResult) Image {
+	image := Image{
+		Key:          result.Key,
+		Pointer:      result.Pointer,
+		Image:        result.Image,
+		Outcome:      result.VerificationOutcome,
+		Error:        errorString(result.Error),
+		Reason:       result.Reason,
+		Exception:    result.Exception,
+		Digest:       result.Digest,
+		Cache:        result.Cache,
+		Mirror:       result.Mirror,
+		MutatedImage: result.MutatedImage,
+		Duration:     Duration(result.Duration),
+	}
+	for idx, resp := range result.VerificationResponses {
+		// verification rules not matching the image have an empty response
+		if len(resp.VerificationRule.ImageReferences) == 0 {
+			continue
+		}
+		rule := VerificationRule{
+			Index:           idx,
+			ImageReferences: resp.VerificationRule.ImageReferences,
+			Cached:          resp.Cached,
+			Duration:        Duration(resp.Duration),
+		}
+		for _, err := range resp.Failures {
+			rule.Failures = append(rule.Failures, err.Error())
+		}
+		image.VerificationRules = append(image.VerificationRules, rule)
+	}
+	return image
+}
+
+func (s *Summary) add(outcome imageverifier.VerificationOutcome) {
+	switch outcome {
+	case imageverifier.PASS:
+		s.Pass++
+	case imageverifier.FAIL:
+		s.Fail++
+	case imageverifier.SKIP:
+		s.Skip++
+	case imageverifier.ERROR:
+		s.Error++
+	case imageverifier.EXCEPTED:
+		s.Excepted++
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
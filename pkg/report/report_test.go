@@ -0,0 +1,102 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testResponse() imageverifier.Response {
+	return imageverifier.Response{
+		PolicyResponses: []imageverifier.PolicyResponse{{
+			Policy: v1alpha1.ImageVerificationPolicy{ObjectMeta: metav1.ObjectMeta{Name: "check-images"}},
+			RuleResponses: []imageverifier.RuleResponse{{
+				Rule:                v1alpha1.ImageVerificationRule{Name: "signed"},
+				VerificationOutcome: imageverifier.FAIL,
+				Duration:            1500 * time.Millisecond,
+				VerificationResults: []imageverifier.VerificationResult{{
+					Key:                 "/containers/0/image",
+					Pointer:             "/containers/0/image",
+					Image:               "ghcr.io/nirmata/app:v1",
+					VerificationOutcome: imageverifier.FAIL,
+					Duration:            time.Second,
+					VerificationResponses: []imageverifier.VerificationResponse{{
+						VerificationRule: v1alpha1.VerificationRule{ImageReferences: []string{"ghcr.io/*"}},
+						Failures:         []error{errors.New("no signatures found")},
+						Duration:         250 * time.Millisecond,
+					}, {
+						// not matching the image
+					}},
+				}},
+			}, {
+				Rule:                v1alpha1.ImageVerificationRule{Name: "unmatched"},
+				VerificationOutcome: imageverifier.SKIP,
+			}},
+		}},
+	}
+}
+
+func Test_New(t *testing.T) {
+	report := New(testResponse())
+	assert.Equal(t, SchemaVersion, report.SchemaVersion)
+	assert.Equal(t, Summary{Fail: 1, Skip: 1}, report.Summary)
+	assert.Len(t, report.Policies, 1)
+	assert.Equal(t, "check-images", report.Policies[0].Name)
+	assert.Len(t, report.Policies[0].Rules, 2)
+	rule := report.Policies[0].Rules[0]
+	assert.Equal(t, Duration(1500*time.Millisecond), rule.Duration)
+	assert.Len(t, rule.Images, 1)
+	assert.Equal(t, []VerificationRule{{
+		Index:           0,
+		ImageReferences: []string{"ghcr.io/*"},
+		Failures:        []string{"no signatures found"},
+		Duration:        Duration(250 * time.Millisecond),
+	}}, rule.Images[0].VerificationRules)
+}
+
+func Test_New_Excepted(t *testing.T) {
+	response := testResponse()
+	rule := &response.PolicyResponses[0].RuleResponses[0]
+	rule.VerificationOutcome = imageverifier.EXCEPTED
+	rule.VerificationResults[0].VerificationOutcome = imageverifier.EXCEPTED
+	rule.VerificationResults[0].Exception = "legacy-images"
+
+	report := New(response)
+	assert.Equal(t, Summary{Skip: 1, Excepted: 1}, report.Summary)
+	assert.Equal(t, "legacy-images", report.Policies[0].Rules[0].Images[0].Exception)
+
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, Text, response))
+	assert.Contains(t, out.String(), "Failures waived by policy exception: legacy-images\n")
+}
+
+func Test_Write(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, JSON, testResponse()))
+	var got Report
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, New(testResponse()), got)
+
+	var raw map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &raw))
+	rule := raw["policies"].([]interface{})[0].(map[string]interface{})["rules"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "1.5s", rule["duration"])
+
+	out.Reset()
+	assert.NoError(t, Write(&out, YAML, testResponse()))
+	assert.Contains(t, out.String(), "schemaVersion: v1\n")
+	assert.Contains(t, out.String(), "- no signatures found\n")
+
+	out.Reset()
+	assert.NoError(t, Write(&out, Text, testResponse()))
+	assert.Contains(t, out.String(), "Results for rule: signed, result: FAIL\n")
+
+	assert.Error(t, Write(&out, Format("xml"), testResponse()))
+}
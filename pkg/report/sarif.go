@@ -0,0 +1,168 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "json-image-verification"
+	toolURI      = "https://github.com/nirmata/json-image-verification"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// newSARIF converts the engine response to a SARIF 2.1.0 log with one result per image that failed
+// or could not be verified, and one result per rule that could not be evaluated. The results are
+// located by the JSON pointer of the image in the resource.
+func newSARIF(response imageverifier.Response, opts options) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           toolName,
+				InformationURI: toolURI,
+				Rules:          []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+	for _, p := range response.PolicyResponses {
+		for _, r := range p.RuleResponses {
+			rule := sarifRule{
+				ID:               p.Policy.Name + "/" + r.Rule.Name,
+				Name:             r.Rule.Name,
+				ShortDescription: sarifMessage{Text: fmt.Sprintf("Rule %s of policy %s", r.Rule.Name, p.Policy.Name)},
+			}
+			ruleIndex := len(run.Tool.Driver.Rules)
+			// failures of rules in Audit mode do not block and are reported as warnings
+			level := "error"
+			if !r.Blocking() {
+				level = "warning"
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+
+			if r.Error != nil {
+				run.Results = append(run.Results, sarifResult{
+					RuleID:    rule.ID,
+					RuleIndex: ruleIndex,
+					Level:     level,
+					Message:   sarifMessage{Text: fmt.Sprintf("rule could not be evaluated: %v", r.Error)},
+					Locations: sarifLocations(opts.resourcePath, ""),
+				})
+				continue
+			}
+			for _, result := range r.VerificationResults {
+				var text string
+				switch result.VerificationOutcome {
+				case imageverifier.FAIL:
+					text = fmt.Sprintf("image %s failed verification: %s", result.Image, strings.Join(failures(result), "; "))
+				case imageverifier.ERROR:
+					text = fmt.Sprintf("image %s could not be verified: %v", result.Image, result.Error)
+				default:
+					continue
+				}
+				run.Results = append(run.Results, sarifResult{
+					RuleID:    rule.ID,
+					RuleIndex: ruleIndex,
+					Level:     level,
+					Message:   sarifMessage{Text: text},
+					Locations: sarifLocations(opts.resourcePath, result.Pointer),
+				})
+			}
+		}
+	}
+	return sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	}
+}
+
+func sarifLocations(resourcePath, pointer string) []sarifLocation {
+	if resourcePath == "" && pointer == "" {
+		return nil
+	}
+	var location sarifLocation
+	if resourcePath != "" {
+		location.PhysicalLocation = &sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: resourcePath},
+		}
+	}
+	if pointer != "" {
+		location.LogicalLocations = []sarifLogicalLocation{{
+			FullyQualifiedName: pointer,
+			Kind:               "element",
+		}}
+	}
+	return []sarifLocation{location}
+}
+
+// failures returns the failures of all the verification rules of the image
+func failures(result imageverifier.VerificationResult) []string {
+	var failures []string
+	for _, resp := range result.VerificationResponses {
+		for _, err := range resp.Failures {
+			failures = append(failures, err.Error())
+		}
+	}
+	return failures
+}
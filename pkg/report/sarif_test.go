@@ -0,0 +1,38 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SARIF(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, Write(&out, SARIF, testResponse(), WithResourcePath("payload.json")))
+	var got sarifLog
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, "2.1.0", got.Version)
+	assert.Len(t, got.Runs, 1)
+	run := got.Runs[0]
+	assert.Equal(t, []sarifRule{{
+		ID:               "check-images/signed",
+		Name:             "signed",
+		ShortDescription: sarifMessage{Text: "Rule signed of policy check-images"},
+	}, {
+		ID:               "check-images/unmatched",
+		Name:             "unmatched",
+		ShortDescription: sarifMessage{Text: "Rule unmatched of policy check-images"},
+	}}, run.Tool.Driver.Rules)
+	assert.Equal(t, []sarifResult{{
+		RuleID:    "check-images/signed",
+		RuleIndex: 0,
+		Level:     "error",
+		Message:   sarifMessage{Text: "image ghcr.io/nirmata/app:v1 failed verification: no signatures found"},
+		Locations: []sarifLocation{{
+			PhysicalLocation: &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "payload.json"}},
+			LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "/containers/0/image", Kind: "element"}},
+		}},
+	}}, run.Results)
+}
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"sigs.k8s.io/yaml"
+)
+
+// Format is an output format of the engine response
+type Format string
+
+const (
+	Text  Format = "text"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	SARIF Format = "sarif"
+	JUnit Format = "junit"
+	// PolicyReport is a wg-policy PolicyReport, or ClusterPolicyReport for resources without
+	// namespace, rendered as YAML
+	PolicyReport Format = "policyreport"
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{Text, JSON, YAML, SARIF, JUnit, PolicyReport}
+
+type options struct {
+	resourcePath string
+	timestamp    time.Time
+}
+
+// Option configures how the engine response is written
+type Option func(*options)
+
+// WithResourcePath sets the path of the verified resource, it is used as the artifact location of SARIF results
+func WithResourcePath(path string) Option {
+	return func(o *options) {
+		o.resourcePath = path
+	}
+}
+
+// WithTimestamp sets the time of the verification, it is the timestamp of PolicyReport results and
+// defaults to the time the response is written
+func WithTimestamp(t time.Time) Option {
+	return func(o *options) {
+		o.timestamp = t
+	}
+}
+
+// Write writes the engine response to out in the given format
+func Write(out io.Writer, format Format, response imageverifier.Response, opts ...Option) error {
+	o := newOptions(opts)
+	switch format {
+	case Text:
+		return writeText(out, response)
+	case JSON:
+		b, err := json.MarshalIndent(New(response), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", b)
+		return err
+	case YAML:
+		b, err := yaml.Marshal(New(response))
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(b)
+		return err
+	case SARIF:
+		b, err := json.MarshalIndent(newSARIF(response, o), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", b)
+		return err
+	case JUnit:
+		b, err := xml.MarshalIndent(newJUnit(response), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s%s\n", xml.Header, b)
+		return err
+	case PolicyReport:
+		var r interface{} = NewClusterPolicyReport(response, opts...)
+		if isNamespaced(response, o) {
+			r = NewPolicyReport(response, opts...)
+		}
+		b, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(b)
+		return err
+	default:
+		return fmt.Errorf("unsupported output format %q, must be one of %v", format, Formats)
+	}
+}
+
+// writeText writes the human readable output of the engine response
+func writeText(out io.Writer, response imageverifier.Response) error {
+	fmt.Fprintln(out, "Verification Result:")
+	for _, p := range response.PolicyResponses {
+		fmt.Fprintf(out, "Results for policy: %s\n", p.Policy.Name)
+		for _, r := range p.RuleResponses {
+			if r.ValidationFailureAction == v1alpha1.Audit && r.VerificationOutcome != imageverifier.PASS && r.VerificationOutcome != imageverifier.SKIP {
+				fmt.Fprintf(out, "Results for rule: %s, result: %s (audit)\n", r.Rule.Name, r.VerificationOutcome)
+			} else {
+				fmt.Fprintf(out, "Results for rule: %s, result: %s\n", r.Rule.Name, r.VerificationOutcome)
+			}
+			if r.Error != nil {
+				fmt.Fprintf(out, "Error encountered: %v\n", r.Error)
+			}
+			for _, resp := range r.VerificationResults {
+				fmt.Fprintf(out, "Verifying image: %s, result: %s\n", resp.Image, resp.VerificationOutcome)
+				switch resp.VerificationOutcome {
+				case imageverifier.ERROR:
+					fmt.Fprintf(out, "Error encountered: %v\n", resp.Error)
+				case imageverifier.EXCEPTED:
+					fmt.Fprintf(out, "Failures waived by policy exception: %s\n", resp.Exception)
+				case imageverifier.FAIL:
+					fmt.Fprintf(out, "Failures:\n")
+					for _, vresp := range resp.VerificationResponses {
+						for _, err := range vresp.Failures {
+							fmt.Fprintln(out, err)
+						}
+					}
+				}
+			}
+		}
+	}
+	if len(response.Patches) > 0 {
+		patch, err := json.Marshal(response.Patches)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Image digest patch: %s\n", patch)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/nirmata/json-image-verification/pkg/report"
+)
+
+// maxRequestSize is the maximum size of a verification request body
+const maxRequestSize = 10 << 20
+
+// Engine verifies the images of a resource
+type Engine interface {
+	Apply(context.Context, imageverifier.Request) imageverifier.Response
+}
+
+// PolicySource provides the policies used to verify resources
+type PolicySource interface {
+	Policies() []*v1alpha1.ImageVerificationPolicy
+}
+
+// syncer is implemented by the policy sources loading policies asynchronously, the server is not
+// ready until the policies are loaded
+type syncer interface {
+	HasSynced() bool
+}
+
+// StaticPolicies is a policy source returning a fixed set of policies
+type StaticPolicies []*v1alpha1.ImageVerificationPolicy
+
+func (p StaticPolicies) Policies() []*v1alpha1.ImageVerificationPolicy {
+	return p
+}
+
+// ExceptionSource provides the policy exceptions applied when verifying resources
+type ExceptionSource interface {
+	Exceptions() []*v1alpha1.PolicyException
+}
+
+// StaticExceptions is an exception source returning a fixed set of exceptions
+type StaticExceptions []*v1alpha1.PolicyException
+
+func (e StaticExceptions) Exceptions() []*v1alpha1.PolicyException {
+	return e
+}
+
+// WithExceptions returns a policy source applying the exceptions of the exception source, it is
+// synced once both sources are synced
+func WithExceptions(policies PolicySource, exceptions ExceptionSource) PolicySource {
+	return policiesWithExceptions{PolicySource: policies, ExceptionSource: exceptions}
+}
+
+type policiesWithExceptions struct {
+	PolicySource
+	ExceptionSource
+}
+
+func (s policiesWithExceptions) HasSynced() bool {
+	for _, source := range []interface{}{s.PolicySource, s.ExceptionSource} {
+		if synced, ok := source.(syncer); ok && !synced.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Exceptions returns the exceptions of the policy source, or nil when the source does not
+// provide exceptions
+func Exceptions(policies PolicySource) []*v1alpha1.PolicyException {
+	if source, ok := policies.(ExceptionSource); ok {
+		return source.Exceptions()
+	}
+	return nil
+}
+
+// VerifyRequest is the body of a verification request, all the policies are applied when no
+// policy name is given
+type VerifyRequest struct {
+	Resource interface{} `json:"resource"`
+	Policies []string    `json:"policies,omitempty"`
+}
+
+// Server exposes the engine over HTTP
+type Server struct {
+	engine   Engine
+	policies PolicySource
+	ready    atomic.Bool
+}
+
+func New(engine Engine, policies PolicySource) *Server {
+	s := &Server{
+		engine:   engine,
+		policies: policies,
+	}
+	s.ready.Store(true)
+	return s
+}
+
+// Handler returns the HTTP handler of the server. It serves the verification API on
+// POST /v1/verify, liveness on /healthz and readiness on /readyz.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/verify", s.verify)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if synced, ok := s.policies.(syncer); !s.ready.Load() || (ok && !synced.HasSynced()) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// Run serves on the listener until ctx is done, then stops accepting requests and waits up to
+// shutdownTimeout for the pending requests to complete
+func (s *Server) Run(ctx context.Context, listener net.Listener, shutdownTimeout time.Duration) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.ready.Store(false)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown server: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) verify(w http.ResponseWriter, r *http.Request) {
+	var request VerifyRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err := decoder.Decode(&request); err != nil {
+		httpError(w, http.StatusBadRequest, fmt.Errorf("failed to decode request: %w", err))
+		return
+	}
+	if request.Resource == nil {
+		httpError(w, http.StatusBadRequest, errors.New("resource is required"))
+		return
+	}
+	policies, err := selectPolicies(s.policies.Policies(), request.Policies)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := s.engine.Apply(r.Context(), imageverifier.Request{
+		Policies:   policies,
+		Exceptions: Exceptions(s.policies),
+		Resource:   request.Resource,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report.New(response)); err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// selectPolicies returns the policies with the given names, or all the policies when no name is given
+func selectPolicies(policies []*v1alpha1.ImageVerificationPolicy, names []string) ([]*v1alpha1.ImageVerificationPolicy, error) {
+	if len(names) == 0 {
+		return policies, nil
+	}
+	byName := make(map[string]*v1alpha1.ImageVerificationPolicy, len(policies))
+	for _, p := range policies {
+		byName[p.Name] = p
+	}
+	selected := make([]*v1alpha1.ImageVerificationPolicy, 0, len(names))
+	for _, name := range names {
+		p, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("policy %s not found", name)
+		}
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func httpError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/nirmata/json-image-verification/pkg/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy(t *testing.T, name string) *v1alpha1.ImageVerificationPolicy {
+	var pol v1alpha1.ImageVerificationPolicy
+	err := json.Unmarshal([]byte(`{"metadata":{"name":"`+name+`"},"spec":{"rules":[{"name":"any","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/*"]}]}]}}`), &pol)
+	assert.NoError(t, err)
+	return &pol
+}
+
+func Test_Verify(t *testing.T) {
+	s := New(imageverifier.NewEngineFromDClient(nil), StaticPolicies{testPolicy(t, "first"), testPolicy(t, "second")})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		wantCode     int
+		wantPolicies []string
+	}{{
+		name:         "all policies",
+		method:       http.MethodPost,
+		body:         `{"resource":{"family":"sample","containerDefinitions":[{"image":"ghcr.io/nirmata/app:v1"}]}}`,
+		wantCode:     http.StatusOK,
+		wantPolicies: []string{"first", "second"},
+	}, {
+		name:         "selected policies",
+		method:       http.MethodPost,
+		body:         `{"resource":{"family":"sample","containerDefinitions":[{"image":"ghcr.io/nirmata/app:v1"}]},"policies":["second"]}`,
+		wantCode:     http.StatusOK,
+		wantPolicies: []string{"second"},
+	}, {
+		name:     "unknown policy",
+		method:   http.MethodPost,
+		body:     `{"resource":{"family":"sample"},"policies":["third"]}`,
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "missing resource",
+		method:   http.MethodPost,
+		body:     `{}`,
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "invalid body",
+		method:   http.MethodPost,
+		body:     `{`,
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "wrong method",
+		method:   http.MethodGet,
+		wantCode: http.StatusMethodNotAllowed,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+"/v1/verify", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.wantCode, resp.StatusCode)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var got report.Report
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			var names []string
+			for _, p := range got.Policies {
+				names = append(names, p.Name)
+				assert.Equal(t, imageverifier.PASS, p.Rules[0].Outcome)
+			}
+			assert.Equal(t, tt.wantPolicies, names)
+		})
+	}
+}
+
+func Test_Run(t *testing.T) {
+	s := New(imageverifier.NewEngineFromDClient(nil), StaticPolicies{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx, listener, time.Second)
+	}()
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		resp, err := http.Get("http://" + listener.Addr().String() + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	assert.False(t, s.ready.Load())
+}
+
+type unsyncedPolicies struct {
+	StaticPolicies
+}
+
+func (unsyncedPolicies) HasSynced() bool {
+	return false
+}
+
+func Test_Readiness(t *testing.T) {
+	s := New(imageverifier.NewEngineFromDClient(nil), unsyncedPolicies{})
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+type unsyncedExceptions struct {
+	StaticExceptions
+}
+
+func (unsyncedExceptions) HasSynced() bool {
+	return false
+}
+
+func Test_Verify_Exceptions(t *testing.T) {
+	var pol v1alpha1.ImageVerificationPolicy
+	err := json.Unmarshal([]byte(`{"metadata":{"name":"digests"},"spec":{"rules":[{"name":"any","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/*"],"requireDigest":true}]}]}}`), &pol)
+	assert.NoError(t, err)
+	var exception v1alpha1.PolicyException
+	err = json.Unmarshal([]byte(`{"metadata":{"name":"legacy"},"spec":{"exceptions":[{"policyName":"digests"}],"expires":"2999-01-01T00:00:00Z"}}`), &exception)
+	assert.NoError(t, err)
+
+	s := New(imageverifier.NewEngineFromDClient(nil), WithExceptions(StaticPolicies{&pol}, StaticExceptions{&exception}))
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/verify", strings.NewReader(`{"resource":{"family":"sample","containerDefinitions":[{"image":"ghcr.io/nirmata/app:v1"}]}}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var got report.Report
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, imageverifier.EXCEPTED, got.Policies[0].Rules[0].Outcome)
+	assert.Equal(t, "legacy", got.Policies[0].Rules[0].Images[0].Exception)
+
+	s = New(imageverifier.NewEngineFromDClient(nil), WithExceptions(StaticPolicies{&pol}, unsyncedExceptions{}))
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
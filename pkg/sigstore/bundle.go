@@ -0,0 +1,56 @@
+package sigstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// bundleMediaTypePrefix is the prefix of the media types of the bundles, it is also the prefix of
+// the artifact types of the OCI referrers storing bundles
+const bundleMediaTypePrefix = "application/vnd.dev.sigstore.bundle"
+
+// bundleVersions are the supported media types of the bundles by minor version
+var bundleVersions = map[string]int{
+	"application/vnd.dev.sigstore.bundle+json;version=0.1": 1,
+	"application/vnd.dev.sigstore.bundle+json;version=0.2": 2,
+	"application/vnd.dev.sigstore.bundle+json;version=0.3": 3,
+	"application/vnd.dev.sigstore.bundle.v0.3+json":        3,
+}
+
+// IsBundleMediaType returns true when the media type, or the artifact type of an OCI referrer, is
+// the one of a Sigstore bundle
+func IsBundleMediaType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, bundleMediaTypePrefix)
+}
+
+// Bundle is a Sigstore bundle, it holds a message signature or a DSSE envelope along with the
+// material needed to verify it offline: the signing certificate or the hint of the signing key,
+// the transparency log entries and the signed timestamps.
+type Bundle struct {
+	*protobundle.Bundle
+	// version is the minor version of the bundle format
+	version int
+}
+
+// ParseBundle parses the JSON encoding of a bundle of version 0.1 to 0.3
+func ParseBundle(data []byte) (*Bundle, error) {
+	var pb protobundle.Bundle
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &pb); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	version, ok := bundleVersions[pb.GetMediaType()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported bundle media type %q", pb.GetMediaType())
+	}
+	if pb.GetVerificationMaterial() == nil {
+		return nil, errors.New("bundle has no verification material")
+	}
+	if pb.GetMessageSignature() == nil && pb.GetDsseEnvelope() == nil {
+		return nil, errors.New("bundle has no message signature nor DSSE envelope")
+	}
+	return &Bundle{Bundle: &pb, version: version}, nil
+}
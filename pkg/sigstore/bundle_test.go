@@ -0,0 +1,61 @@
+package sigstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsBundleMediaType(t *testing.T) {
+	assert.True(t, IsBundleMediaType("application/vnd.dev.sigstore.bundle.v0.3+json"))
+	assert.True(t, IsBundleMediaType("application/vnd.dev.sigstore.bundle+json;version=0.1"))
+	assert.False(t, IsBundleMediaType("application/vnd.dev.cosign.simplesigning.v1+json"))
+}
+
+func Test_ParseBundle(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		wantErr string
+	}{{
+		name:    "v0.1",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.bundle+json;version=0.1","verificationMaterial":{"publicKey":{"hint":"key"}},"messageSignature":{"signature":"AAAA"}}`,
+		version: 1,
+	}, {
+		name:    "v0.2",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.bundle+json;version=0.2","verificationMaterial":{"publicKey":{"hint":"key"}},"messageSignature":{"signature":"AAAA"}}`,
+		version: 2,
+	}, {
+		name:    "v0.3",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","verificationMaterial":{"publicKey":{"hint":"key"}},"dsseEnvelope":{"payload":"e30=","payloadType":"application/vnd.in-toto+json"}}`,
+		version: 3,
+	}, {
+		name:    "invalid json",
+		data:    `{"mediaType":`,
+		wantErr: "failed to parse bundle",
+	}, {
+		name:    "unsupported media type",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.bundle+json;version=1.0"}`,
+		wantErr: "unsupported bundle media type",
+	}, {
+		name:    "missing verification material",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","messageSignature":{"signature":"AAAA"}}`,
+		wantErr: "bundle has no verification material",
+	}, {
+		name:    "missing content",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","verificationMaterial":{"publicKey":{"hint":"key"}}}`,
+		wantErr: "bundle has no message signature nor DSSE envelope",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ParseBundle([]byte(tt.data))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.version, b.version)
+		})
+	}
+}
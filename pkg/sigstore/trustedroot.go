@@ -0,0 +1,183 @@
+package sigstore
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	prototrustroot "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/tuf"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// trustedRootMediaTypes are the supported media types of the trusted roots
+var trustedRootMediaTypes = []string{
+	"application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+	"application/vnd.dev.sigstore.trustedroot.v0.2+json",
+}
+
+// TrustedRoot is the set of Sigstore services trusted to verify bundles: the certificate authorities
+// issuing the signing certificates, the transparency logs, the certificate transparency logs and
+// the timestamp authorities. It is read from a trustedRoot.json, as distributed by the Sigstore TUF
+// repositories, so that bundles are verified without accessing any of these services.
+type TrustedRoot struct {
+	certificateAuthorities []certificateAuthority
+	timestampAuthorities   []certificateAuthority
+	// tlogs and ctlogs are the transparency logs and the certificate transparency logs by hex
+	// encoded log ID
+	tlogs  map[string]transparencyLog
+	ctlogs map[string]transparencyLog
+}
+
+// certificateAuthority is a certificate chain, ordered from the leaf-most certificate to the root
+type certificateAuthority struct {
+	chain    []*x509.Certificate
+	validFor timeRange
+}
+
+func (c certificateAuthority) root() *x509.Certificate {
+	return c.chain[len(c.chain)-1]
+}
+
+type transparencyLog struct {
+	key      crypto.PublicKey
+	verifier signature.Verifier
+	validFor timeRange
+}
+
+// timeRange is a validity period, it has no end when end is zero
+type timeRange struct {
+	start time.Time
+	end   time.Time
+}
+
+func (r timeRange) contains(t time.Time) bool {
+	if t.Before(r.start) {
+		return false
+	}
+	return r.end.IsZero() || !t.After(r.end)
+}
+
+// LoadTrustedRoot reads the trusted root from a trustedRoot.json file
+func LoadTrustedRoot(path string) (*TrustedRoot, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted root: %w", err)
+	}
+	return ParseTrustedRoot(data)
+}
+
+// ParseTrustedRoot parses the JSON encoding of a trusted root
+func ParseTrustedRoot(data []byte) (*TrustedRoot, error) {
+	var pb prototrustroot.TrustedRoot
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &pb); err != nil {
+		return nil, fmt.Errorf("failed to parse trusted root: %w", err)
+	}
+	if !contains(trustedRootMediaTypes, pb.GetMediaType()) {
+		return nil, fmt.Errorf("unsupported trusted root media type %q", pb.GetMediaType())
+	}
+	root := &TrustedRoot{
+		tlogs:  map[string]transparencyLog{},
+		ctlogs: map[string]transparencyLog{},
+	}
+	for _, ca := range pb.GetCertificateAuthorities() {
+		authority, err := parseCertificateAuthority(ca)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate authority %s: %w", ca.GetUri(), err)
+		}
+		root.certificateAuthorities = append(root.certificateAuthorities, authority)
+	}
+	for _, tsa := range pb.GetTimestampAuthorities() {
+		authority, err := parseCertificateAuthority(tsa)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp authority %s: %w", tsa.GetUri(), err)
+		}
+		root.timestampAuthorities = append(root.timestampAuthorities, authority)
+	}
+	for _, tlog := range pb.GetTlogs() {
+		if err := addTransparencyLog(root.tlogs, tlog); err != nil {
+			return nil, fmt.Errorf("invalid transparency log %s: %w", tlog.GetBaseUrl(), err)
+		}
+	}
+	for _, ctlog := range pb.GetCtlogs() {
+		if err := addTransparencyLog(root.ctlogs, ctlog); err != nil {
+			return nil, fmt.Errorf("invalid certificate transparency log %s: %w", ctlog.GetBaseUrl(), err)
+		}
+	}
+	return root, nil
+}
+
+func parseCertificateAuthority(pb *prototrustroot.CertificateAuthority) (certificateAuthority, error) {
+	var ca certificateAuthority
+	for _, cert := range pb.GetCertChain().GetCertificates() {
+		parsed, err := x509.ParseCertificate(cert.GetRawBytes())
+		if err != nil {
+			return ca, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		ca.chain = append(ca.chain, parsed)
+	}
+	if len(ca.chain) == 0 {
+		return ca, errors.New("certificate chain is empty")
+	}
+	ca.validFor = parseTimeRange(pb.GetValidFor())
+	return ca, nil
+}
+
+func addTransparencyLog(logs map[string]transparencyLog, pb *prototrustroot.TransparencyLogInstance) error {
+	if len(pb.GetLogId().GetKeyId()) == 0 {
+		return errors.New("log ID is missing")
+	}
+	key, err := x509.ParsePKIXPublicKey(pb.GetPublicKey().GetRawBytes())
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	}
+	verifier, err := signature.LoadVerifier(key, crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("failed to load public key: %w", err)
+	}
+	logs[hex.EncodeToString(pb.GetLogId().GetKeyId())] = transparencyLog{
+		key:      key,
+		verifier: verifier,
+		validFor: parseTimeRange(pb.GetPublicKey().GetValidFor()),
+	}
+	return nil
+}
+
+func parseTimeRange(pb *protocommon.TimeRange) timeRange {
+	var r timeRange
+	if pb.GetStart() != nil {
+		r.start = pb.GetStart().AsTime()
+	}
+	if pb.GetEnd() != nil {
+		r.end = pb.GetEnd().AsTime()
+	}
+	return r
+}
+
+// ctlogKeys returns the keys of the certificate transparency logs valid at the time
+func (r *TrustedRoot) ctlogKeys(t time.Time) *cosign.TrustedTransparencyLogPubKeys {
+	keys := cosign.NewTrustedTransparencyLogPubKeys()
+	for id, ctlog := range r.ctlogs {
+		if ctlog.validFor.contains(t) {
+			keys.Keys[id] = cosign.TransparencyLogPubKey{PubKey: ctlog.key, Status: tuf.Active}
+		}
+	}
+	return &keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package sigstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseTrustedRoot(t *testing.T) {
+	s := newVirtualSigstore(t)
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{{
+		name: "valid",
+		data: string(s.trustedRoot(t)),
+	}, {
+		name: "empty",
+		data: `{"mediaType":"application/vnd.dev.sigstore.trustedroot.v0.2+json"}`,
+	}, {
+		name:    "invalid json",
+		data:    `{"mediaType":`,
+		wantErr: "failed to parse trusted root",
+	}, {
+		name:    "unsupported media type",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.trustedroot+json;version=1.0"}`,
+		wantErr: "unsupported trusted root media type",
+	}, {
+		name:    "invalid certificate",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.trustedroot.v0.2+json","certificateAuthorities":[{"uri":"https://fulcio.example.com","certChain":{"certificates":[{"rawBytes":"AAAA"}]}}]}`,
+		wantErr: "invalid certificate authority https://fulcio.example.com",
+	}, {
+		name:    "missing log ID",
+		data:    `{"mediaType":"application/vnd.dev.sigstore.trustedroot.v0.2+json","tlogs":[{"baseUrl":"https://rekor.example.com","publicKey":{"rawBytes":"AAAA"}}]}`,
+		wantErr: "log ID is missing",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTrustedRoot([]byte(tt.data))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_LoadTrustedRoot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trustedRoot.json")
+	assert.NoError(t, os.WriteFile(path, newVirtualSigstore(t).trustedRoot(t), 0o600))
+	root, err := LoadTrustedRoot(path)
+	assert.NoError(t, err)
+	assert.Len(t, root.certificateAuthorities, 1)
+	assert.Len(t, root.timestampAuthorities, 1)
+	assert.Len(t, root.tlogs, 1)
+	assert.Len(t, root.ctlogs, 1)
+
+	_, err = LoadTrustedRoot(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to read trusted root")
+}
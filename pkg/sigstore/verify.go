@@ -0,0 +1,531 @@
+package sigstore
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyverno/kyverno/ext/wildcard"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	protorekor "github.com/sigstore/protobuf-specs/gen/pb-go/rekor/v1"
+	"github.com/sigstore/rekor/pkg/generated/models"
+	rekorverify "github.com/sigstore/rekor/pkg/verify"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+	"github.com/sigstore/timestamp-authority/pkg/verification"
+)
+
+// InTotoPayloadType is the payload type of the DSSE envelopes signing in-toto statements
+const InTotoPayloadType = "application/vnd.in-toto+json"
+
+// Policy is the policy the bundles of an image are verified against
+type Policy struct {
+	// Digest is the digest of the image manifest signed by the bundles, e.g. sha256:3a5b...
+	Digest string
+	// PublicKey verifies the bundles signed with a key. The bundles signed with a certificate are
+	// verified with the certificate authorities of the trusted root when it is nil, and rejected
+	// otherwise.
+	PublicKey crypto.PublicKey
+	// Issuer and Subject are the wildcard patterns the OIDC issuer and the subject of the signing
+	// certificates must match, they are not checked when empty
+	Issuer  string
+	Subject string
+	// IgnoreTlog skips the verification of the transparency log entries of the bundles
+	IgnoreTlog bool
+	// IgnoreSCT skips the verification of the certificate transparency timestamps embedded in the
+	// signing certificates
+	IgnoreSCT bool
+}
+
+// Result is the outcome of the verification of a bundle
+type Result struct {
+	// Statement is the in-toto statement signed by the DSSE envelope of the bundle, it is nil for
+	// message signatures
+	Statement map[string]interface{}
+	// Certificate is the signing certificate, it is nil for bundles signed with a key
+	Certificate *x509.Certificate
+	// Timestamps are the verified times of the signature, from the inclusion promises of the
+	// transparency log entries and from the signed timestamps
+	Timestamps []time.Time
+}
+
+// Verify verifies that the bundle signs the image digest of the policy, with the services of the
+// trusted root only. It verifies the signature, the transparency log entries with their inclusion
+// proofs and promises, the signed timestamps and, for bundles signed with a certificate, the
+// certificate chain at the signing time, the embedded SCTs and the identity of the certificate.
+func (r *TrustedRoot) Verify(ctx context.Context, b *Bundle, policy Policy) (*Result, error) {
+	c, err := bundleContent(b, policy.Digest)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := signingCertificate(b.GetVerificationMaterial())
+	if err != nil {
+		return nil, err
+	}
+	var key crypto.PublicKey
+	switch {
+	case cert != nil && policy.PublicKey != nil:
+		return nil, errors.New("bundle is signed with a certificate rather than a key")
+	case cert != nil:
+		key = cert.PublicKey
+	case policy.PublicKey != nil:
+		key = policy.PublicKey
+	default:
+		return nil, errors.New("bundle is signed with a key, a public key is required to verify it")
+	}
+	verifier, err := signature.LoadVerifier(key, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load verifier: %w", err)
+	}
+	if err := c.verifySignature(verifier); err != nil {
+		return nil, fmt.Errorf("failed to verify signature: %w", err)
+	}
+
+	result := &Result{Statement: c.statement, Certificate: cert}
+	if !policy.IgnoreTlog {
+		entries := b.GetVerificationMaterial().GetTlogEntries()
+		if len(entries) == 0 {
+			return nil, errors.New("bundle has no transparency log entry")
+		}
+		for _, entry := range entries {
+			integratedTime, promised, err := r.verifyTlogEntry(ctx, entry, b.version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to verify transparency log entry %d: %w", entry.GetLogIndex(), err)
+			}
+			if err := c.matchTlogEntry(entry, cert, key); err != nil {
+				return nil, fmt.Errorf("transparency log entry %d does not match the bundle: %w", entry.GetLogIndex(), err)
+			}
+			if promised {
+				result.Timestamps = append(result.Timestamps, integratedTime)
+			}
+		}
+	}
+	for _, ts := range b.GetVerificationMaterial().GetTimestampVerificationData().GetRfc3161Timestamps() {
+		t, err := r.verifySignedTimestamp(ts.GetSignedTimestamp(), c.signature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify signed timestamp: %w", err)
+		}
+		result.Timestamps = append(result.Timestamps, t)
+	}
+
+	if cert != nil {
+		if err := r.verifyCertificate(ctx, cert, result.Timestamps, policy); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// content is the signed content of a bundle
+type content struct {
+	signature []byte
+	// digest is the SHA-256 digest of the signed message, or of the payload of the DSSE envelope
+	digest    []byte
+	envelope  *envelope
+	statement map[string]interface{}
+}
+
+type envelope struct {
+	payloadType string
+	payload     []byte
+}
+
+// bundleContent returns the content of the bundle once checked it signs the image digest
+func bundleContent(b *Bundle, imageDigest string) (*content, error) {
+	algorithm, hexDigest, ok := strings.Cut(imageDigest, ":")
+	if !ok || algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported image digest %q", imageDigest)
+	}
+	if sig := b.GetMessageSignature(); sig != nil {
+		if sig.GetMessageDigest().GetAlgorithm() != protocommon.HashAlgorithm_SHA2_256 {
+			return nil, fmt.Errorf("unsupported message digest algorithm %s", sig.GetMessageDigest().GetAlgorithm())
+		}
+		if hex.EncodeToString(sig.GetMessageDigest().GetDigest()) != hexDigest {
+			return nil, fmt.Errorf("bundle does not sign the image digest %s", imageDigest)
+		}
+		return &content{signature: sig.GetSignature(), digest: sig.GetMessageDigest().GetDigest()}, nil
+	}
+
+	env := b.GetDsseEnvelope()
+	if env.GetPayloadType() != InTotoPayloadType {
+		return nil, fmt.Errorf("unsupported DSSE payload type %q", env.GetPayloadType())
+	}
+	if len(env.GetSignatures()) != 1 {
+		return nil, fmt.Errorf("DSSE envelope must have exactly one signature, found %d", len(env.GetSignatures()))
+	}
+	var statement map[string]interface{}
+	if err := json.Unmarshal(env.GetPayload(), &statement); err != nil {
+		return nil, fmt.Errorf("failed to parse in-toto statement: %w", err)
+	}
+	if !matchSubject(statement, hexDigest) {
+		return nil, fmt.Errorf("in-toto statement has no subject with the image digest %s", imageDigest)
+	}
+	digest := sha256.Sum256(env.GetPayload())
+	return &content{
+		signature: env.GetSignatures()[0].GetSig(),
+		digest:    digest[:],
+		envelope:  &envelope{payloadType: env.GetPayloadType(), payload: env.GetPayload()},
+		statement: statement,
+	}, nil
+}
+
+// matchSubject returns true when a subject of the in-toto statement has the SHA-256 digest
+func matchSubject(statement map[string]interface{}, hexDigest string) bool {
+	subjects, _ := statement["subject"].([]interface{})
+	for _, subject := range subjects {
+		s, _ := subject.(map[string]interface{})
+		digests, _ := s["digest"].(map[string]interface{})
+		if digests["sha256"] == hexDigest {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *content) verifySignature(verifier signature.Verifier) error {
+	if c.envelope != nil {
+		return verifier.VerifySignature(bytes.NewReader(c.signature), bytes.NewReader(pae(c.envelope.payloadType, c.envelope.payload)))
+	}
+	return verifier.VerifySignature(bytes.NewReader(c.signature), nil, options.WithDigest(c.digest))
+}
+
+// pae is the pre-authentication encoding of the DSSE envelopes that their signatures sign
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// signingCertificate returns the signing certificate of the verification material, it is nil
+// when the bundle is signed with a key
+func signingCertificate(material *protobundle.VerificationMaterial) (*x509.Certificate, error) {
+	var raw []byte
+	switch content := material.GetContent().(type) {
+	case *protobundle.VerificationMaterial_Certificate:
+		raw = content.Certificate.GetRawBytes()
+	case *protobundle.VerificationMaterial_X509CertificateChain:
+		certs := content.X509CertificateChain.GetCertificates()
+		if len(certs) == 0 {
+			return nil, errors.New("bundle certificate chain is empty")
+		}
+		raw = certs[0].GetRawBytes()
+	case *protobundle.VerificationMaterial_PublicKey:
+		return nil, nil
+	default:
+		return nil, errors.New("bundle has no certificate nor public key")
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// verifyTlogEntry verifies the inclusion promise and the inclusion proof of the transparency log
+// entry and returns its integrated time, promised is true when the integrated time is signed by
+// the log. Bundles of version 0.1 require an inclusion promise, later versions an inclusion proof.
+func (r *TrustedRoot) verifyTlogEntry(ctx context.Context, entry *protorekor.TransparencyLogEntry, version int) (time.Time, bool, error) {
+	logID := hex.EncodeToString(entry.GetLogId().GetKeyId())
+	tlog, ok := r.tlogs[logID]
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("transparency log %s is not in the trusted root", logID)
+	}
+	promise, proof := entry.GetInclusionPromise(), entry.GetInclusionProof()
+	if version == 1 && promise == nil {
+		return time.Time{}, false, errors.New("inclusion promise is missing")
+	}
+	if version > 1 && proof == nil {
+		return time.Time{}, false, errors.New("inclusion proof is missing")
+	}
+
+	integratedTime := time.Unix(entry.GetIntegratedTime(), 0)
+	body := base64.StdEncoding.EncodeToString(entry.GetCanonicalizedBody())
+	logIndex, integrated := entry.GetLogIndex(), entry.GetIntegratedTime()
+	anon := &models.LogEntryAnon{
+		Body:           body,
+		IntegratedTime: &integrated,
+		LogID:          &logID,
+		LogIndex:       &logIndex,
+		Verification:   &models.LogEntryAnonVerification{},
+	}
+	if promise != nil {
+		anon.Verification.SignedEntryTimestamp = promise.GetSignedEntryTimestamp()
+		if err := rekorverify.VerifySignedEntryTimestamp(ctx, anon, tlog.verifier); err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to verify inclusion promise: %w", err)
+		}
+	}
+	if proof != nil {
+		if proof.GetCheckpoint().GetEnvelope() == "" {
+			return time.Time{}, false, errors.New("inclusion proof has no checkpoint")
+		}
+		hashes := make([]string, 0, len(proof.GetHashes()))
+		for _, h := range proof.GetHashes() {
+			hashes = append(hashes, hex.EncodeToString(h))
+		}
+		checkpoint := proof.GetCheckpoint().GetEnvelope()
+		rootHash := hex.EncodeToString(proof.GetRootHash())
+		proofIndex, treeSize := proof.GetLogIndex(), proof.GetTreeSize()
+		anon.Verification.InclusionProof = &models.InclusionProof{
+			Checkpoint: &checkpoint,
+			Hashes:     hashes,
+			LogIndex:   &proofIndex,
+			RootHash:   &rootHash,
+			TreeSize:   &treeSize,
+		}
+		if err := rekorverify.VerifyInclusion(ctx, anon); err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to verify inclusion proof: %w", err)
+		}
+		if err := rekorverify.VerifyCheckpointSignature(anon, tlog.verifier); err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to verify checkpoint: %w", err)
+		}
+	}
+	// the key of the log must be valid when the entry was integrated, whether it signed the
+	// inclusion promise or the checkpoint of the inclusion proof
+	if !tlog.validFor.contains(integratedTime) {
+		return time.Time{}, false, fmt.Errorf("integrated time %s is outside the validity of the transparency log key", integratedTime.UTC().Format(time.RFC3339))
+	}
+	return integratedTime, promise != nil, nil
+}
+
+// matchTlogEntry checks the transparency log entry records the signature of the content, with the
+// signing certificate or key
+func (c *content) matchTlogEntry(entry *protorekor.TransparencyLogEntry, cert *x509.Certificate, key crypto.PublicKey) error {
+	var body struct {
+		APIVersion string          `json:"apiVersion"`
+		Kind       string          `json:"kind"`
+		Spec       json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(entry.GetCanonicalizedBody(), &body); err != nil {
+		return fmt.Errorf("failed to parse entry body: %w", err)
+	}
+	if kv := entry.GetKindVersion(); kv.GetKind() != body.Kind || kv.GetVersion() != body.APIVersion {
+		return fmt.Errorf("entry kind %s/%s does not match its body", kv.GetKind(), kv.GetVersion())
+	}
+	type hash struct {
+		Algorithm string `json:"algorithm"`
+		Value     string `json:"value"`
+	}
+	digest := hex.EncodeToString(c.digest)
+	switch body.Kind {
+	case "hashedrekord":
+		var spec struct {
+			Data struct {
+				Hash hash `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   []byte `json:"content"`
+				PublicKey struct {
+					Content []byte `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+		}
+		if err := json.Unmarshal(body.Spec, &spec); err != nil {
+			return fmt.Errorf("failed to parse hashedrekord entry: %w", err)
+		}
+		if c.envelope != nil {
+			return errors.New("hashedrekord entry does not record DSSE envelopes")
+		}
+		if spec.Data.Hash.Algorithm != "sha256" || spec.Data.Hash.Value != digest {
+			return errors.New("entry digest does not match the message digest")
+		}
+		if !bytes.Equal(spec.Signature.Content, c.signature) {
+			return errors.New("entry signature does not match the message signature")
+		}
+		return matchKey(spec.Signature.PublicKey.Content, cert, key)
+	case "dsse":
+		var spec struct {
+			PayloadHash hash `json:"payloadHash"`
+			Signatures  []struct {
+				Signature []byte `json:"signature"`
+				Verifier  []byte `json:"verifier"`
+			} `json:"signatures"`
+		}
+		if err := json.Unmarshal(body.Spec, &spec); err != nil {
+			return fmt.Errorf("failed to parse dsse entry: %w", err)
+		}
+		if c.envelope == nil {
+			return errors.New("dsse entry does not record message signatures")
+		}
+		if spec.PayloadHash.Algorithm != "sha256" || spec.PayloadHash.Value != digest {
+			return errors.New("entry payload hash does not match the envelope payload")
+		}
+		for _, sig := range spec.Signatures {
+			if bytes.Equal(sig.Signature, c.signature) {
+				return matchKey(sig.Verifier, cert, key)
+			}
+		}
+		return errors.New("entry signatures do not match the envelope signature")
+	case "intoto":
+		var spec struct {
+			Content struct {
+				Envelope struct {
+					Signatures []struct {
+						Sig       []byte `json:"sig"`
+						PublicKey []byte `json:"publicKey"`
+					} `json:"signatures"`
+				} `json:"envelope"`
+				PayloadHash hash `json:"payloadHash"`
+			} `json:"content"`
+		}
+		if err := json.Unmarshal(body.Spec, &spec); err != nil {
+			return fmt.Errorf("failed to parse intoto entry: %w", err)
+		}
+		if c.envelope == nil {
+			return errors.New("intoto entry does not record message signatures")
+		}
+		if spec.Content.PayloadHash.Algorithm != "sha256" || spec.Content.PayloadHash.Value != digest {
+			return errors.New("entry payload hash does not match the envelope payload")
+		}
+		// the signatures of intoto entries are recorded base64 encoded
+		encoded := []byte(base64.StdEncoding.EncodeToString(c.signature))
+		for _, sig := range spec.Content.Envelope.Signatures {
+			if bytes.Equal(sig.Sig, encoded) {
+				return matchKey(sig.PublicKey, cert, key)
+			}
+		}
+		return errors.New("entry signatures do not match the envelope signature")
+	default:
+		return fmt.Errorf("unsupported entry kind %q", body.Kind)
+	}
+}
+
+// matchKey checks the PEM certificate or public key recorded by a transparency log entry is the
+// signing certificate or key
+func matchKey(recorded []byte, cert *x509.Certificate, key crypto.PublicKey) error {
+	block, _ := pem.Decode(recorded)
+	if block == nil {
+		return errors.New("entry has no PEM certificate nor public key")
+	}
+	if cert != nil {
+		if !bytes.Equal(block.Bytes, cert.Raw) {
+			return errors.New("entry certificate does not match the signing certificate")
+		}
+		return nil
+	}
+	var pub crypto.PublicKey
+	switch block.Type {
+	case "CERTIFICATE":
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse entry certificate: %w", err)
+		}
+		pub = c.PublicKey
+	default:
+		p, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse entry public key: %w", err)
+		}
+		pub = p
+	}
+	if err := cryptoutils.EqualKeys(pub, key); err != nil {
+		return errors.New("entry public key does not match the signing key")
+	}
+	return nil
+}
+
+// verifySignedTimestamp verifies the RFC 3161 timestamp response of the signature with the
+// timestamp authorities of the trusted root and returns the signed time
+func (r *TrustedRoot) verifySignedTimestamp(tsr, sig []byte) (time.Time, error) {
+	var errs []error
+	for _, tsa := range r.timestampAuthorities {
+		var intermediates []*x509.Certificate
+		if len(tsa.chain) > 2 {
+			intermediates = tsa.chain[1 : len(tsa.chain)-1]
+		}
+		ts, err := verification.VerifyTimestampResponse(tsr, bytes.NewReader(sig), verification.VerifyOpts{
+			TSACertificate: tsa.chain[0],
+			Intermediates:  intermediates,
+			Roots:          []*x509.Certificate{tsa.root()},
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !tsa.validFor.contains(ts.Time) {
+			errs = append(errs, fmt.Errorf("timestamp %s is outside the validity of the timestamp authority", ts.Time.UTC().Format(time.RFC3339)))
+			continue
+		}
+		return ts.Time, nil
+	}
+	if len(errs) == 0 {
+		return time.Time{}, errors.New("trusted root has no timestamp authority")
+	}
+	return time.Time{}, errors.Join(errs...)
+}
+
+// verifyCertificate verifies the signing certificate chains to a certificate authority of the
+// trusted root at every verified timestamp, its embedded SCTs and its identity
+func (r *TrustedRoot) verifyCertificate(ctx context.Context, cert *x509.Certificate, timestamps []time.Time, policy Policy) error {
+	if len(timestamps) == 0 {
+		return errors.New("bundle has no verified timestamp to check the signing certificate with")
+	}
+	var chain []*x509.Certificate
+	for _, t := range timestamps {
+		var err error
+		if chain, err = r.verifyCertificateAt(cert, t); err != nil {
+			return err
+		}
+	}
+	if !policy.IgnoreSCT {
+		if err := cosign.VerifyEmbeddedSCT(ctx, chain, r.ctlogKeys(cert.NotBefore)); err != nil {
+			return fmt.Errorf("failed to verify SCT: %w", err)
+		}
+	}
+	if policy.Subject != "" {
+		// the subject matches any SAN of the certificate, as cosign does
+		sans := cryptoutils.GetSubjectAlternateNames(cert)
+		if !slices.ContainsFunc(sans, func(san string) bool { return wildcard.Match(policy.Subject, san) }) {
+			return fmt.Errorf("subject mismatch: expected %s, received %s", policy.Subject, strings.Join(sans, ", "))
+		}
+	}
+	if policy.Issuer != "" {
+		ce := cosign.CertExtensions{Cert: cert}
+		if issuer := ce.GetIssuer(); !wildcard.Match(policy.Issuer, issuer) {
+			return fmt.Errorf("issuer mismatch: expected %s, received %s", policy.Issuer, issuer)
+		}
+	}
+	return nil
+}
+
+// verifyCertificateAt returns the chain of the certificate to a certificate authority of the
+// trusted root valid at the time
+func (r *TrustedRoot) verifyCertificateAt(cert *x509.Certificate, t time.Time) ([]*x509.Certificate, error) {
+	var errs []error
+	for _, ca := range r.certificateAuthorities {
+		if !ca.validFor.contains(t) {
+			continue
+		}
+		roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+		roots.AddCert(ca.root())
+		for _, c := range ca.chain[:len(ca.chain)-1] {
+			intermediates.AddCert(c)
+		}
+		chains, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   t,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return chains[0], nil
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no certificate authority of the trusted root is valid at %s", t.UTC().Format(time.RFC3339))
+	}
+	return nil, fmt.Errorf("signing certificate is not trusted at %s: %w", t.UTC().Format(time.RFC3339), errors.Join(errs...))
+}
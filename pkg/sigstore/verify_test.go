@@ -0,0 +1,602 @@
+package sigstore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/digitorus/timestamp"
+	ct "github.com/google/certificate-transparency-go"
+	cttls "github.com/google/certificate-transparency-go/tls"
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	protodsse "github.com/sigstore/protobuf-specs/gen/pb-go/dsse"
+	protorekor "github.com/sigstore/protobuf-specs/gen/pb-go/rekor/v1"
+	prototrustroot "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/sigstore/rekor/pkg/util"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/transparency-dev/merkle/rfc6962"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	testIssuer  = "https://token.actions.githubusercontent.com"
+	testSubject = "signer@example.com"
+)
+
+// virtualSigstore is an in-memory Sigstore deployment: a certificate authority, a transparency
+// log, a certificate transparency log and a timestamp authority
+type virtualSigstore struct {
+	now             time.Time
+	caRoot          *x509.Certificate
+	caIntermediate  *x509.Certificate
+	intermediateKey *ecdsa.PrivateKey
+	tlogKey         *ecdsa.PrivateKey
+	ctlogKey        *ecdsa.PrivateKey
+	tsaRoot         *x509.Certificate
+	tsaLeaf         *x509.Certificate
+	tsaKey          *ecdsa.PrivateKey
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, pub crypto.PublicKey, priv crypto.Signer) *x509.Certificate {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+func caTemplate(name string, now time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+}
+
+func newVirtualSigstore(t *testing.T) *virtualSigstore {
+	t.Helper()
+	s := &virtualSigstore{now: time.Now().Truncate(time.Second)}
+	rootKey := generateKey(t)
+	s.caRoot = createCertificate(t, caTemplate("fulcio root", s.now), caTemplate("fulcio root", s.now), rootKey.Public(), rootKey)
+	s.intermediateKey = generateKey(t)
+	s.caIntermediate = createCertificate(t, caTemplate("fulcio intermediate", s.now), s.caRoot, s.intermediateKey.Public(), rootKey)
+	s.tlogKey = generateKey(t)
+	s.ctlogKey = generateKey(t)
+
+	tsaRootKey := generateKey(t)
+	s.tsaRoot = createCertificate(t, caTemplate("tsa root", s.now), caTemplate("tsa root", s.now), tsaRootKey.Public(), tsaRootKey)
+	eku, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 8}})
+	assert.NoError(t, err)
+	s.tsaKey = generateKey(t)
+	s.tsaLeaf = createCertificate(t, &x509.Certificate{
+		SerialNumber:    big.NewInt(s.now.UnixNano()),
+		Subject:         pkix.Name{CommonName: "tsa"},
+		NotBefore:       s.now.Add(-time.Hour),
+		NotAfter:        s.now.Add(time.Hour),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{2, 5, 29, 37}, Critical: true, Value: eku}},
+	}, s.tsaRoot, s.tsaKey.Public(), tsaRootKey)
+	return s
+}
+
+func logID(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	assert.NoError(t, err)
+	id := sha256.Sum256(der)
+	return id[:]
+}
+
+func transparencyLogInstance(t *testing.T, key *ecdsa.PrivateKey, start time.Time) *prototrustroot.TransparencyLogInstance {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	assert.NoError(t, err)
+	return &prototrustroot.TransparencyLogInstance{
+		BaseUrl:       "https://log.example.com",
+		HashAlgorithm: protocommon.HashAlgorithm_SHA2_256,
+		PublicKey: &protocommon.PublicKey{
+			RawBytes:   der,
+			KeyDetails: protocommon.PublicKeyDetails_PKIX_ECDSA_P256_SHA_256,
+			ValidFor:   &protocommon.TimeRange{Start: timestamppb.New(start)},
+		},
+		LogId: &protocommon.LogId{KeyId: logID(t, key)},
+	}
+}
+
+func certificateChain(certs ...*x509.Certificate) *protocommon.X509CertificateChain {
+	chain := &protocommon.X509CertificateChain{}
+	for _, cert := range certs {
+		chain.Certificates = append(chain.Certificates, &protocommon.X509Certificate{RawBytes: cert.Raw})
+	}
+	return chain
+}
+
+// trustedRoot returns the JSON trusted root of the virtual sigstore
+func (s *virtualSigstore) trustedRoot(t *testing.T) []byte {
+	t.Helper()
+	start := s.now.Add(-time.Hour)
+	root := &prototrustroot.TrustedRoot{
+		MediaType: "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		Tlogs:     []*prototrustroot.TransparencyLogInstance{transparencyLogInstance(t, s.tlogKey, start)},
+		Ctlogs:    []*prototrustroot.TransparencyLogInstance{transparencyLogInstance(t, s.ctlogKey, start)},
+		CertificateAuthorities: []*prototrustroot.CertificateAuthority{{
+			Uri:       "https://fulcio.example.com",
+			CertChain: certificateChain(s.caIntermediate, s.caRoot),
+			ValidFor:  &protocommon.TimeRange{Start: timestamppb.New(start)},
+		}},
+		TimestampAuthorities: []*prototrustroot.CertificateAuthority{{
+			Uri:       "https://tsa.example.com",
+			CertChain: certificateChain(s.tsaLeaf, s.tsaRoot),
+			ValidFor:  &protocommon.TimeRange{Start: timestamppb.New(start)},
+		}},
+	}
+	data, err := protojson.Marshal(root)
+	assert.NoError(t, err)
+	return data
+}
+
+// signingCertificate issues a short lived signing certificate of the identity, with the URIs as
+// additional SANs and an SCT of the certificate transparency log embedded when sct is true
+func (s *virtualSigstore) signingCertificate(t *testing.T, key *ecdsa.PrivateKey, issuer, subject string, uris []*url.URL, sct bool) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(s.now.UnixNano()),
+		NotBefore:       s.now.Add(-time.Minute),
+		NotAfter:        s.now.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{subject},
+		URIs:            uris,
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}, Value: []byte(issuer)}},
+	}
+	cert := createCertificate(t, template, s.caIntermediate, key.Public(), s.intermediateKey)
+	if !sct {
+		return cert
+	}
+
+	// the SCT signs the certificate without the SCT extension, that is added once signed
+	ts := uint64(s.now.UnixMilli())
+	leaf := ct.MerkleTreeLeaf{
+		Version:  ct.V1,
+		LeafType: ct.TimestampedEntryLeafType,
+		TimestampedEntry: &ct.TimestampedEntry{
+			Timestamp: ts,
+			EntryType: ct.PrecertLogEntryType,
+			PrecertEntry: &ct.PreCert{
+				IssuerKeyHash:  sha256.Sum256(s.caIntermediate.RawSubjectPublicKeyInfo),
+				TBSCertificate: cert.RawTBSCertificate,
+			},
+		},
+	}
+	signed := ct.SignedCertificateTimestamp{SCTVersion: ct.V1, Timestamp: ts}
+	copy(signed.LogID.KeyID[:], logID(t, s.ctlogKey))
+	input, err := ct.SerializeSCTSignatureInput(signed, ct.LogEntry{Leaf: leaf})
+	assert.NoError(t, err)
+	hash := sha256.Sum256(input)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.ctlogKey, hash[:])
+	assert.NoError(t, err)
+	signed.Signature = ct.DigitallySigned{
+		Algorithm: cttls.SignatureAndHashAlgorithm{Hash: cttls.SHA256, Signature: cttls.ECDSA},
+		Signature: sig,
+	}
+	serialized, err := cttls.Marshal(signed)
+	assert.NoError(t, err)
+	list, err := cttls.Marshal(ctx509.SignedCertificateTimestampList{SCTList: []ctx509.SerializedSCT{{Val: serialized}}})
+	assert.NoError(t, err)
+	value, err := asn1.Marshal(list)
+	assert.NoError(t, err)
+	template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{Id: asn1.ObjectIdentifier(ctx509.OIDExtensionCTSCT), Value: value})
+	return createCertificate(t, template, s.caIntermediate, key.Public(), s.intermediateKey)
+}
+
+// tlogEntry records the body in a transparency log of two entries, signed with the key
+func (s *virtualSigstore) tlogEntry(t *testing.T, key *ecdsa.PrivateKey, kind string, body []byte, promise, proof bool) *protorekor.TransparencyLogEntry {
+	t.Helper()
+	id := logID(t, key)
+	entry := &protorekor.TransparencyLogEntry{
+		LogIndex:          0,
+		LogId:             &protocommon.LogId{KeyId: id},
+		KindVersion:       &protorekor.KindVersion{Kind: kind, Version: "0.0.1"},
+		IntegratedTime:    s.now.Unix(),
+		CanonicalizedBody: body,
+	}
+	if promise {
+		payload, err := json.Marshal(struct {
+			Body           string `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogID          string `json:"logID"`
+			LogIndex       int64  `json:"logIndex"`
+		}{base64.StdEncoding.EncodeToString(body), entry.IntegratedTime, hex.EncodeToString(id), entry.LogIndex})
+		assert.NoError(t, err)
+		hash := sha256.Sum256(payload)
+		set, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+		assert.NoError(t, err)
+		entry.InclusionPromise = &protorekor.InclusionPromise{SignedEntryTimestamp: set}
+	}
+	if proof {
+		leaf := rfc6962.DefaultHasher.HashLeaf(body)
+		sibling := rfc6962.DefaultHasher.HashLeaf([]byte("another entry"))
+		rootHash := rfc6962.DefaultHasher.HashChildren(leaf, sibling)
+		signer, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+		assert.NoError(t, err)
+		checkpoint, err := util.CreateAndSignCheckpoint(context.Background(), "log.example.com", 1, 2, rootHash, signer)
+		assert.NoError(t, err)
+		entry.InclusionProof = &protorekor.InclusionProof{
+			LogIndex:   0,
+			RootHash:   rootHash,
+			TreeSize:   2,
+			Hashes:     [][]byte{sibling},
+			Checkpoint: &protorekor.Checkpoint{Envelope: string(checkpoint)},
+		}
+	}
+	return entry
+}
+
+// signedTimestamp returns the RFC 3161 timestamp response of the signature
+func (s *virtualSigstore) signedTimestamp(t *testing.T, sig []byte) []byte {
+	t.Helper()
+	hash := sha256.Sum256(sig)
+	ts := timestamp.Timestamp{
+		HashAlgorithm:     crypto.SHA256,
+		HashedMessage:     hash[:],
+		Time:              s.now,
+		Policy:            asn1.ObjectIdentifier{1, 2, 3, 4, 1},
+		AddTSACertificate: true,
+	}
+	resp, err := ts.CreateResponseWithOpts(s.tsaLeaf, s.tsaKey, crypto.SHA256)
+	assert.NoError(t, err)
+	return resp
+}
+
+// bundleOptions describes a bundle signed by the virtual sigstore
+type bundleOptions struct {
+	mediaType string
+	// key signs the bundle with a key rather than a certificate
+	key bool
+	// dsse signs an in-toto statement rather than the image digest
+	dsse    bool
+	issuer  string
+	subject string
+	// uris are SANs of the signing certificate added after the subject
+	uris  []*url.URL
+	noSCT bool
+	// promise and proof add the inclusion promise and the inclusion proof to the tlog entry, the
+	// bundle has no tlog entry when both are false
+	promise bool
+	proof   bool
+	// tlogKey signs the tlog entry, it is the key of the virtual transparency log when nil
+	tlogKey   *ecdsa.PrivateKey
+	timestamp bool
+}
+
+// sign returns the JSON bundle signing the image digest and the signing key
+func (s *virtualSigstore) sign(t *testing.T, digest string, opts bundleOptions) ([]byte, *ecdsa.PrivateKey) {
+	t.Helper()
+	if opts.mediaType == "" {
+		opts.mediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+	}
+	if opts.issuer == "" {
+		opts.issuer = testIssuer
+	}
+	if opts.subject == "" {
+		opts.subject = testSubject
+	}
+	if opts.tlogKey == nil {
+		opts.tlogKey = s.tlogKey
+	}
+	key := generateKey(t)
+	b := &protobundle.Bundle{MediaType: opts.mediaType, VerificationMaterial: &protobundle.VerificationMaterial{}}
+	var keyPEM []byte
+	if opts.key {
+		der, err := x509.MarshalPKIXPublicKey(key.Public())
+		assert.NoError(t, err)
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		b.VerificationMaterial.Content = &protobundle.VerificationMaterial_PublicKey{PublicKey: &protocommon.PublicKeyIdentifier{Hint: "key"}}
+	} else {
+		cert := s.signingCertificate(t, key, opts.issuer, opts.subject, opts.uris, !opts.noSCT)
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		if opts.mediaType == "application/vnd.dev.sigstore.bundle.v0.3+json" {
+			b.VerificationMaterial.Content = &protobundle.VerificationMaterial_Certificate{Certificate: &protocommon.X509Certificate{RawBytes: cert.Raw}}
+		} else {
+			b.VerificationMaterial.Content = &protobundle.VerificationMaterial_X509CertificateChain{X509CertificateChain: certificateChain(cert)}
+		}
+	}
+
+	hexDigest := digest[len("sha256:"):]
+	var sig, body []byte
+	var kind string
+	if opts.dsse {
+		payload := []byte(fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"ghcr.io/org/app","digest":{"sha256":%q}}],"predicateType":"https://slsa.dev/provenance/v1","predicate":{"builder":{"id":"https://github.com/org/app"}}}`, hexDigest))
+		hash := sha256.Sum256(pae(InTotoPayloadType, payload))
+		var err error
+		sig, err = ecdsa.SignASN1(rand.Reader, key, hash[:])
+		assert.NoError(t, err)
+		b.Content = &protobundle.Bundle_DsseEnvelope{DsseEnvelope: &protodsse.Envelope{
+			Payload:     payload,
+			PayloadType: InTotoPayloadType,
+			Signatures:  []*protodsse.Signature{{Sig: sig}},
+		}}
+		payloadHash := sha256.Sum256(payload)
+		kind = "dsse"
+		body, err = json.Marshal(map[string]interface{}{
+			"apiVersion": "0.0.1",
+			"kind":       kind,
+			"spec": map[string]interface{}{
+				"payloadHash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(payloadHash[:])},
+				"signatures":  []map[string][]byte{{"signature": sig, "verifier": keyPEM}},
+			},
+		})
+		assert.NoError(t, err)
+	} else {
+		hash, err := hex.DecodeString(hexDigest)
+		assert.NoError(t, err)
+		sig, err = ecdsa.SignASN1(rand.Reader, key, hash)
+		assert.NoError(t, err)
+		b.Content = &protobundle.Bundle_MessageSignature{MessageSignature: &protocommon.MessageSignature{
+			MessageDigest: &protocommon.HashOutput{Algorithm: protocommon.HashAlgorithm_SHA2_256, Digest: hash},
+			Signature:     sig,
+		}}
+		kind = "hashedrekord"
+		body, err = json.Marshal(map[string]interface{}{
+			"apiVersion": "0.0.1",
+			"kind":       kind,
+			"spec": map[string]interface{}{
+				"data":      map[string]interface{}{"hash": map[string]string{"algorithm": "sha256", "value": hexDigest}},
+				"signature": map[string]interface{}{"content": sig, "publicKey": map[string][]byte{"content": keyPEM}},
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	if opts.promise || opts.proof {
+		b.VerificationMaterial.TlogEntries = []*protorekor.TransparencyLogEntry{s.tlogEntry(t, opts.tlogKey, kind, body, opts.promise, opts.proof)}
+	}
+	if opts.timestamp {
+		b.VerificationMaterial.TimestampVerificationData = &protobundle.TimestampVerificationData{
+			Rfc3161Timestamps: []*protocommon.RFC3161SignedTimestamp{{SignedTimestamp: s.signedTimestamp(t, sig)}},
+		}
+	}
+	data, err := protojson.Marshal(b)
+	assert.NoError(t, err)
+	return data, key
+}
+
+func Test_Verify(t *testing.T) {
+	s := newVirtualSigstore(t)
+	root, err := ParseTrustedRoot(s.trustedRoot(t))
+	assert.NoError(t, err)
+	manifest := sha256.Sum256([]byte("manifest"))
+	digest := "sha256:" + hex.EncodeToString(manifest[:])
+	workflowURI, err := url.Parse("https://github.com/org/app/.github/workflows/release.yml@refs/heads/main")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		opts       bundleOptions
+		tamper     func(*protobundle.Bundle)
+		policy     Policy
+		withKey    bool
+		digest     string
+		timestamps int
+		statement  bool
+		wantErr    string
+	}{{
+		name:       "keyless",
+		opts:       bundleOptions{promise: true, proof: true, timestamp: true},
+		policy:     Policy{Issuer: testIssuer, Subject: testSubject},
+		timestamps: 2,
+	}, {
+		name:       "keyless wildcard identity",
+		opts:       bundleOptions{promise: true, proof: true},
+		policy:     Policy{Issuer: "https://token.actions.*", Subject: "*@example.com"},
+		timestamps: 1,
+	}, {
+		name:       "keyless v0.1",
+		opts:       bundleOptions{mediaType: "application/vnd.dev.sigstore.bundle+json;version=0.1", promise: true},
+		timestamps: 1,
+	}, {
+		name:       "keyless signed timestamp only",
+		opts:       bundleOptions{proof: true, timestamp: true},
+		timestamps: 1,
+	}, {
+		name:       "keyless dsse",
+		opts:       bundleOptions{dsse: true, promise: true, proof: true},
+		policy:     Policy{Issuer: testIssuer, Subject: testSubject},
+		timestamps: 1,
+		statement:  true,
+	}, {
+		name:    "key",
+		opts:    bundleOptions{key: true, proof: true},
+		withKey: true,
+	}, {
+		name:    "key ignore tlog",
+		opts:    bundleOptions{key: true},
+		policy:  Policy{IgnoreTlog: true},
+		withKey: true,
+	}, {
+		name:    "key mismatch",
+		opts:    bundleOptions{key: true, proof: true},
+		policy:  Policy{PublicKey: generateKey(t).Public()},
+		wantErr: "failed to verify signature",
+	}, {
+		name:    "key missing",
+		opts:    bundleOptions{key: true, proof: true},
+		wantErr: "a public key is required",
+	}, {
+		name:    "unexpected certificate",
+		opts:    bundleOptions{promise: true, proof: true},
+		withKey: true,
+		wantErr: "signed with a certificate rather than a key",
+	}, {
+		name:    "other digest",
+		opts:    bundleOptions{promise: true, proof: true},
+		digest:  "sha256:" + hex.EncodeToString(make([]byte, 32)),
+		wantErr: "bundle does not sign the image digest",
+	}, {
+		name:    "dsse other digest",
+		opts:    bundleOptions{dsse: true, promise: true, proof: true},
+		digest:  "sha256:" + hex.EncodeToString(make([]byte, 32)),
+		wantErr: "in-toto statement has no subject with the image digest",
+	}, {
+		name:    "subject mismatch",
+		opts:    bundleOptions{promise: true, proof: true},
+		policy:  Policy{Subject: "other@example.com"},
+		wantErr: "subject mismatch",
+	}, {
+		name:       "subject in other SAN",
+		opts:       bundleOptions{uris: []*url.URL{workflowURI}, promise: true, proof: true},
+		policy:     Policy{Subject: "https://github.com/org/app/.github/workflows/*"},
+		timestamps: 1,
+	}, {
+		name:    "subject mismatch any SAN",
+		opts:    bundleOptions{uris: []*url.URL{workflowURI}, promise: true, proof: true},
+		policy:  Policy{Subject: "https://github.com/other/*"},
+		wantErr: "subject mismatch",
+	}, {
+		name:    "issuer mismatch",
+		opts:    bundleOptions{issuer: "https://accounts.example.com", promise: true, proof: true},
+		policy:  Policy{Issuer: testIssuer},
+		wantErr: "issuer mismatch",
+	}, {
+		name:    "missing SCT",
+		opts:    bundleOptions{noSCT: true, promise: true, proof: true},
+		wantErr: "failed to verify SCT",
+	}, {
+		name:       "ignore SCT",
+		opts:       bundleOptions{noSCT: true, promise: true, proof: true},
+		policy:     Policy{IgnoreSCT: true},
+		timestamps: 1,
+	}, {
+		name:    "missing tlog entry",
+		opts:    bundleOptions{timestamp: true},
+		wantErr: "bundle has no transparency log entry",
+	}, {
+		name:       "ignore tlog",
+		opts:       bundleOptions{timestamp: true},
+		policy:     Policy{IgnoreTlog: true},
+		timestamps: 1,
+	}, {
+		name:    "no verified timestamp",
+		opts:    bundleOptions{proof: true},
+		wantErr: "no verified timestamp",
+	}, {
+		name:    "untrusted tlog",
+		opts:    bundleOptions{promise: true, proof: true, tlogKey: generateKey(t)},
+		wantErr: "is not in the trusted root",
+	}, {
+		name:    "v0.1 missing inclusion promise",
+		opts:    bundleOptions{mediaType: "application/vnd.dev.sigstore.bundle+json;version=0.1", proof: true, timestamp: true},
+		wantErr: "inclusion promise is missing",
+	}, {
+		name:    "v0.3 missing inclusion proof",
+		opts:    bundleOptions{promise: true, timestamp: true},
+		wantErr: "inclusion proof is missing",
+	}, {
+		name: "inclusion proof outside tlog key validity",
+		opts: bundleOptions{proof: true, timestamp: true},
+		tamper: func(b *protobundle.Bundle) {
+			// the integrated time is not covered by the checkpoint, only by the key validity
+			b.GetVerificationMaterial().GetTlogEntries()[0].IntegratedTime = s.now.Add(-2 * time.Hour).Unix()
+		},
+		wantErr: "outside the validity of the transparency log key",
+	}, {
+		name: "tampered signature",
+		opts: bundleOptions{promise: true, proof: true},
+		tamper: func(b *protobundle.Bundle) {
+			b.GetMessageSignature().Signature[8] ^= 1
+		},
+		wantErr: "failed to verify signature",
+	}, {
+		name: "tampered inclusion promise",
+		opts: bundleOptions{promise: true, proof: true},
+		tamper: func(b *protobundle.Bundle) {
+			b.GetVerificationMaterial().GetTlogEntries()[0].GetInclusionPromise().SignedEntryTimestamp[8] ^= 1
+		},
+		wantErr: "failed to verify inclusion promise",
+	}, {
+		name: "tampered inclusion proof",
+		opts: bundleOptions{proof: true, timestamp: true},
+		tamper: func(b *protobundle.Bundle) {
+			b.GetVerificationMaterial().GetTlogEntries()[0].GetInclusionProof().Hashes[0][0] ^= 1
+		},
+		wantErr: "failed to verify inclusion proof",
+	}, {
+		name: "tampered checkpoint",
+		opts: bundleOptions{proof: true, timestamp: true},
+		tamper: func(b *protobundle.Bundle) {
+			b.GetVerificationMaterial().GetTlogEntries()[0].GetInclusionProof().GetCheckpoint().Envelope += "\n"
+		},
+		wantErr: "failed to verify checkpoint",
+	}, {
+		name: "tampered signed timestamp",
+		opts: bundleOptions{proof: true, timestamp: true},
+		tamper: func(b *protobundle.Bundle) {
+			ts := b.GetVerificationMaterial().GetTimestampVerificationData().GetRfc3161Timestamps()[0]
+			ts.SignedTimestamp[len(ts.SignedTimestamp)-8] ^= 1
+		},
+		wantErr: "failed to verify signed timestamp",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, key := s.sign(t, digest, tt.opts)
+			b, err := ParseBundle(data)
+			assert.NoError(t, err)
+			if tt.tamper != nil {
+				tt.tamper(b.Bundle)
+			}
+			policy := tt.policy
+			if tt.withKey {
+				policy.PublicKey = key.Public()
+			}
+			policy.Digest = digest
+			if tt.digest != "" {
+				policy.Digest = tt.digest
+			}
+			result, err := root.Verify(context.Background(), b, policy)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, result.Timestamps, tt.timestamps)
+			assert.Equal(t, tt.opts.key, result.Certificate == nil)
+			if tt.statement {
+				assert.Equal(t, "https://slsa.dev/provenance/v1", result.Statement["predicateType"])
+			} else {
+				assert.Nil(t, result.Statement)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// CAFile is the name of the CA certificate file written by Certificates.Write
+	CAFile = "ca.crt"
+	// CertFile is the name of the serving certificate file written by Certificates.Write
+	CertFile = "tls.crt"
+	// KeyFile is the name of the serving key file written by Certificates.Write
+	KeyFile = "tls.key"
+)
+
+// Certificates is a self-signed CA and a serving certificate issued by the CA, all PEM encoded.
+// The CA is the caBundle of the webhook configuration.
+type Certificates struct {
+	CA   []byte
+	Cert []byte
+	Key  []byte
+}
+
+// GenerateCertificates creates a CA and a serving certificate valid for the given DNS names and IP
+// addresses. It is meant for local clusters such as kind, a certificate manager should be used
+// otherwise.
+func GenerateCertificates(hosts []string, validity time.Duration) (*Certificates, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("at least one host is required")
+	}
+	now := time.Now()
+	// tolerate clock skew between the webhook and the API server
+	notBefore := now.Add(-time.Hour)
+	notAfter := now.Add(validity)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "json-image-verification-ca"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serving key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create serving certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode serving key: %w", err)
+	}
+	return &Certificates{
+		CA:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// Write writes the certificates to CAFile, CertFile and KeyFile in dir
+func (c *Certificates) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{CAFile, c.CA, 0o644},
+		{CertFile, c.Cert, 0o644},
+		{KeyFile, c.Key, 0o600},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.data, f.perm); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// ReadCertificates reads the certificates from CAFile, CertFile and KeyFile in dir
+func ReadCertificates(dir string) (*Certificates, error) {
+	var c Certificates
+	files := []struct {
+		name string
+		data *[]byte
+	}{
+		{CAFile, &c.CA},
+		{CertFile, &c.Cert},
+		{KeyFile, &c.Key},
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.name, err)
+		}
+		*f.data = data
+	}
+	return &c, nil
+}
+
+// Verify checks that the serving certificate matches its key, is issued by the CA and is valid for
+// the hosts at the given time
+func (c *Certificates) Verify(hosts []string, at time.Time) error {
+	pair, err := tls.X509KeyPair(c.Cert, c.Key)
+	if err != nil {
+		return fmt.Errorf("invalid serving certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("invalid serving certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(c.CA) {
+		return errors.New("invalid CA certificate")
+	}
+	for _, host := range hosts {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots, CurrentTime: at}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadOrGenerateCertificates returns the certificates of dir when they are still valid for the
+// hosts, so that the CA of the webhook configurations keeps matching the serving certificate across
+// restarts. Otherwise it generates new certificates and writes them to dir, generated is true then.
+func LoadOrGenerateCertificates(dir string, hosts []string, validity time.Duration) (certs *Certificates, generated bool, err error) {
+	if certs, err := ReadCertificates(dir); err == nil && certs.Verify(hosts, time.Now()) == nil {
+		return certs, false, nil
+	}
+	if certs, err = GenerateCertificates(hosts, validity); err != nil {
+		return nil, false, err
+	}
+	if err := certs.Write(dir); err != nil {
+		return nil, false, err
+	}
+	return certs, true, nil
+}
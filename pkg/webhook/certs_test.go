@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoadOrGenerateCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certs, generated, err := LoadOrGenerateCertificates(dir, []string{"verifier.default.svc"}, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, generated)
+
+	// valid certificates are reused so that the registered CA keeps matching
+	reused, generated, err := LoadOrGenerateCertificates(dir, []string{"verifier.default.svc"}, time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, generated)
+	assert.Equal(t, certs, reused)
+
+	// certificates that are not valid for the hosts are replaced
+	other, generated, err := LoadOrGenerateCertificates(dir, []string{"127.0.0.1"}, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, generated)
+	assert.NotEqual(t, certs.CA, other.CA)
+	ca, err := os.ReadFile(filepath.Join(dir, CAFile))
+	assert.NoError(t, err)
+	assert.Equal(t, other.CA, ca)
+
+	// expired certificates are replaced
+	expired, err := GenerateCertificates([]string{"127.0.0.1"}, -time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, expired.Write(dir))
+	renewed, generated, err := LoadOrGenerateCertificates(dir, []string{"127.0.0.1"}, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, generated)
+	assert.NoError(t, renewed.Verify([]string{"127.0.0.1"}, time.Now()))
+}
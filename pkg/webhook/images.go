@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/nirmata/json-image-verification/pkg/server"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageVerificationPath is the path of the image verification webhook
+const ImageVerificationPath = "/verify-images"
+
+// ImageVerifier verifies the images of the admitted objects with the policies of the source, the
+// objects are verified as plain JSON in the same way as non Kubernetes resources
+type ImageVerifier struct {
+	engine   server.Engine
+	policies server.PolicySource
+}
+
+func NewImageVerifier(engine server.Engine, policies server.PolicySource) *ImageVerifier {
+	return &ImageVerifier{
+		engine:   engine,
+		policies: policies,
+	}
+}
+
+// HasSynced returns true once the policies are loaded
+func (v *ImageVerifier) HasSynced() bool {
+	if synced, ok := v.policies.(interface{ HasSynced() bool }); ok {
+		return synced.HasSynced()
+	}
+	return true
+}
+
+// Verify denies the objects when a rule in Enforce mode fails or errors, the outcome of every
+// verified image and the failures of the rules in Audit mode are returned in the warnings of the
+// response
+func (v *ImageVerifier) Verify(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return allowed()
+	}
+	var resource interface{}
+	if err := json.Unmarshal(request.Object.Raw, &resource); err != nil {
+		return denied(apierrors.NewBadRequest(fmt.Sprintf("failed to decode object: %v", err)).Status())
+	}
+	response := v.engine.Apply(ctx, imageverifier.Request{
+		Policies:   v.policies.Policies(),
+		Exceptions: server.Exceptions(v.policies),
+		Resource:   resource,
+	})
+
+	var warnings, failures []string
+	for _, policy := range response.PolicyResponses {
+		for _, rule := range policy.RuleResponses {
+			name := policy.Policy.Name + "/" + rule.Rule.Name
+			if rule.Blocking() {
+				failures = append(failures, fmt.Sprintf("%s: %s", name, rule.VerificationOutcome))
+			} else if rule.VerificationOutcome == imageverifier.FAIL || rule.VerificationOutcome == imageverifier.ERROR {
+				warnings = append(warnings, fmt.Sprintf("%s: %s in audit mode, the object is not blocked", name, rule.VerificationOutcome))
+			}
+			if rule.Error != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: %s: %v", name, rule.VerificationOutcome, rule.Error))
+			}
+			for _, result := range rule.VerificationResults {
+				warnings = append(warnings, fmt.Sprintf("%s: %s", name, imageMessage(result)))
+			}
+		}
+	}
+	if len(failures) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true, Warnings: warnings}
+	}
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("image verification failed: %s", strings.Join(failures, ", ")),
+		},
+		Warnings: warnings,
+	}
+}
+
+// imageMessage describes the outcome of the verification of an image
+func imageMessage(result imageverifier.VerificationResult) string {
+	message := fmt.Sprintf("image %s %s", result.Image, result.VerificationOutcome)
+	var details []string
+	if result.Reason != "" {
+		details = append(details, string(result.Reason))
+	}
+	if result.Error != nil {
+		details = append(details, result.Error.Error())
+	}
+	if result.Exception != "" {
+		details = append(details, "waived by policy exception "+result.Exception)
+	}
+	for _, response := range result.VerificationResponses {
+		for _, failure := range response.Failures {
+			details = append(details, failure.Error())
+		}
+	}
+	if len(details) > 0 {
+		message += ": " + strings.Join(details, "; ")
+	}
+	return message
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	"github.com/nirmata/json-image-verification/pkg/imageverifier"
+	"github.com/nirmata/json-image-verification/pkg/server"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeEngine returns the outcome of the images by image name
+type fakeEngine map[string]imageverifier.VerificationOutcome
+
+func (e fakeEngine) Apply(_ context.Context, request imageverifier.Request) imageverifier.Response {
+	spec := request.Resource.(map[string]interface{})["spec"].(map[string]interface{})
+	rule := imageverifier.RuleResponse{
+		Rule:                    v1alpha1.ImageVerificationRule{Name: "images"},
+		ValidationFailureAction: v1alpha1.Enforce,
+		VerificationOutcome:     imageverifier.PASS,
+	}
+	if _, ok := request.Resource.(map[string]interface{})["audit"]; ok {
+		rule.ValidationFailureAction = v1alpha1.Audit
+	}
+	for _, c := range spec["containers"].([]interface{}) {
+		image := c.(map[string]interface{})["image"].(string)
+		result := imageverifier.VerificationResult{Image: image, VerificationOutcome: e[image]}
+		switch e[image] {
+		case imageverifier.FAIL:
+			result.VerificationResponses = []imageverifier.VerificationResponse{{Failures: []error{errors.New("signature mismatch")}}}
+			rule.VerificationOutcome = imageverifier.FAIL
+		case imageverifier.ERROR:
+			result.Error = errors.New("registry unavailable")
+			rule.VerificationOutcome = imageverifier.ERROR
+		case imageverifier.EXCEPTED:
+			result.Exception = "legacy"
+			if rule.VerificationOutcome == imageverifier.PASS {
+				rule.VerificationOutcome = imageverifier.EXCEPTED
+			}
+		}
+		rule.VerificationResults = append(rule.VerificationResults, result)
+	}
+	return imageverifier.Response{
+		Resource: request.Resource,
+		PolicyResponses: []imageverifier.PolicyResponse{{
+			Policy:        v1alpha1.ImageVerificationPolicy{ObjectMeta: metav1.ObjectMeta{Name: "pods"}},
+			RuleResponses: []imageverifier.RuleResponse{rule},
+		}},
+	}
+}
+
+func pod(images ...string) []byte {
+	var containers []interface{}
+	for _, image := range images {
+		containers = append(containers, map[string]interface{}{"image": image})
+	}
+	b, _ := json.Marshal(map[string]interface{}{"kind": "Pod", "spec": map[string]interface{}{"containers": containers}})
+	return b
+}
+
+// auditPod returns a pod verified by rules in Audit mode
+func auditPod(images ...string) []byte {
+	var object map[string]interface{}
+	_ = json.Unmarshal(pod(images...), &object)
+	object["audit"] = true
+	b, _ := json.Marshal(object)
+	return b
+}
+
+func Test_ImageVerifier(t *testing.T) {
+	verifier := NewImageVerifier(fakeEngine{
+		"ghcr.io/nirmata/pass:v1":   imageverifier.PASS,
+		"ghcr.io/nirmata/fail:v1":   imageverifier.FAIL,
+		"ghcr.io/nirmata/error:v1":  imageverifier.ERROR,
+		"ghcr.io/nirmata/legacy:v1": imageverifier.EXCEPTED,
+	}, server.StaticPolicies{})
+
+	tests := []struct {
+		name         string
+		operation    admissionv1.Operation
+		object       []byte
+		wantAllowed  bool
+		wantMessage  string
+		wantWarnings []string
+	}{{
+		name:         "pass",
+		operation:    admissionv1.Create,
+		object:       pod("ghcr.io/nirmata/pass:v1"),
+		wantAllowed:  true,
+		wantWarnings: []string{"pods/images: image ghcr.io/nirmata/pass:v1 PASS"},
+	}, {
+		name:        "fail",
+		operation:   admissionv1.Update,
+		object:      pod("ghcr.io/nirmata/pass:v1", "ghcr.io/nirmata/fail:v1"),
+		wantMessage: "image verification failed: pods/images: FAIL",
+		wantWarnings: []string{
+			"pods/images: image ghcr.io/nirmata/pass:v1 PASS",
+			"pods/images: image ghcr.io/nirmata/fail:v1 FAIL: signature mismatch",
+		},
+	}, {
+		name:         "error",
+		operation:    admissionv1.Create,
+		object:       pod("ghcr.io/nirmata/error:v1"),
+		wantMessage:  "image verification failed: pods/images: ERROR",
+		wantWarnings: []string{"pods/images: image ghcr.io/nirmata/error:v1 ERROR: registry unavailable"},
+	}, {
+		name:         "excepted",
+		operation:    admissionv1.Create,
+		object:       pod("ghcr.io/nirmata/legacy:v1"),
+		wantAllowed:  true,
+		wantWarnings: []string{"pods/images: image ghcr.io/nirmata/legacy:v1 EXCEPTED: waived by policy exception legacy"},
+	}, {
+		name:        "audit",
+		operation:   admissionv1.Create,
+		object:      auditPod("ghcr.io/nirmata/fail:v1"),
+		wantAllowed: true,
+		wantWarnings: []string{
+			"pods/images: FAIL in audit mode, the object is not blocked",
+			"pods/images: image ghcr.io/nirmata/fail:v1 FAIL: signature mismatch",
+		},
+	}, {
+		name:        "delete",
+		operation:   admissionv1.Delete,
+		wantAllowed: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := verifier.Verify(context.Background(), &admissionv1.AdmissionRequest{
+				Operation: tt.operation,
+				Object:    runtime.RawExtension{Raw: tt.object},
+			})
+			assert.Equal(t, tt.wantAllowed, got.Allowed)
+			assert.Equal(t, tt.wantWarnings, got.Warnings)
+			if tt.wantAllowed {
+				assert.Nil(t, got.Result)
+				return
+			}
+			assert.Equal(t, int32(http.StatusForbidden), got.Result.Code)
+			assert.Equal(t, tt.wantMessage, got.Result.Message)
+		})
+	}
+}
+
+type unsyncedPolicies struct {
+	server.StaticPolicies
+}
+
+func (unsyncedPolicies) HasSynced() bool {
+	return false
+}
+
+func Test_ImageVerifier_Readiness(t *testing.T) {
+	s := New(WithImageVerifier(NewImageVerifier(fakeEngine{}, unsyncedPolicies{})))
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidatePolicy rejects the image verification policies failing the semantic validation, the
+// structure of the policies is already checked by the API server against the CRD schema
+func ValidatePolicy(_ context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return allowed()
+	}
+	kind := v1alpha1.SchemeGroupVersion.WithKind("ImageVerificationPolicy")
+	if request.Kind.Group != kind.Group || request.Kind.Kind != kind.Kind {
+		return denied(apierrors.NewBadRequest(fmt.Sprintf("unexpected kind %s, expected %s", request.Kind.String(), kind.String())).Status())
+	}
+	var policy v1alpha1.ImageVerificationPolicy
+	if err := json.Unmarshal(request.Object.Raw, &policy); err != nil {
+		return denied(apierrors.NewBadRequest(fmt.Sprintf("failed to decode policy: %v", err)).Status())
+	}
+	if errs := policy.Validate(); len(errs) > 0 {
+		return denied(apierrors.NewInvalid(kind.GroupKind(), request.Name, errs).Status())
+	}
+	return allowed()
+}
+
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func denied(status metav1.Status) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: false, Result: &status}
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nirmata/json-image-verification/pkg/apis/v1alpha1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// PolicyWebhookName is the name of the policy validation webhook and of its configuration
+	PolicyWebhookName = "validate-policy.nirmata.io"
+	// ImageWebhookName is the name of the image verification webhook and of its configuration
+	ImageWebhookName = "verify-images.nirmata.io"
+)
+
+// PolicyWebhookConfiguration returns the configuration sending the policy creations and updates
+// to the webhook described by clientConfig
+func PolicyWebhookConfiguration(clientConfig admissionregistrationv1.WebhookClientConfig) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: PolicyWebhookName},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name:         PolicyWebhookName,
+			ClientConfig: clientConfig,
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{v1alpha1.GroupName},
+					APIVersions: []string{v1alpha1.SchemeGroupVersion.Version},
+					Resources:   []string{"imageverificationpolicies"},
+					Scope:       ptr.To(admissionregistrationv1.ClusterScope),
+				},
+			}},
+			FailurePolicy:           &failurePolicy,
+			SideEffects:             &sideEffects,
+			AdmissionReviewVersions: []string{"v1"},
+			TimeoutSeconds:          ptr.To[int32](10),
+		}},
+	}
+}
+
+// ImageWebhookConfiguration returns the configuration sending the creations and updates of the
+// given resources to the image verification webhook described by clientConfig. The objects of the
+// kube-system namespace are never sent to the webhook to avoid blocking the cluster components,
+// nor the ones of the namespace of the webhook service so that the webhook pods can be recreated
+// while the webhook is down.
+func ImageWebhookConfiguration(clientConfig admissionregistrationv1.WebhookClientConfig, rules []admissionregistrationv1.Rule) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	excluded := []string{"kube-system"}
+	if service := clientConfig.Service; service != nil && service.Namespace != "" && service.Namespace != "kube-system" {
+		excluded = append(excluded, service.Namespace)
+	}
+	webhook := admissionregistrationv1.ValidatingWebhook{
+		Name:         ImageWebhookName,
+		ClientConfig: clientConfig,
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "kubernetes.io/metadata.name",
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   excluded,
+			}},
+		},
+		FailurePolicy:           &failurePolicy,
+		SideEffects:             &sideEffects,
+		AdmissionReviewVersions: []string{"v1"},
+		// image verification calls registries and is slower than the policy validation
+		TimeoutSeconds: ptr.To[int32](30),
+	}
+	for _, rule := range rules {
+		webhook.Rules = append(webhook.Rules, admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule:       rule,
+		})
+	}
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ImageWebhookName},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{webhook},
+	}
+}
+
+// ParseRule parses a resource of the form [group/]version/resource into an admission rule, the
+// group is empty for the core resources
+func ParseRule(resource string) (admissionregistrationv1.Rule, error) {
+	parts := strings.Split(resource, "/")
+	for _, part := range parts {
+		if part == "" {
+			return admissionregistrationv1.Rule{}, fmt.Errorf("invalid resource %q, must be [group/]version/resource", resource)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return admissionregistrationv1.Rule{APIGroups: []string{""}, APIVersions: []string{parts[0]}, Resources: []string{parts[1]}}, nil
+	case 3:
+		return admissionregistrationv1.Rule{APIGroups: []string{parts[0]}, APIVersions: []string{parts[1]}, Resources: []string{parts[2]}}, nil
+	default:
+		return admissionregistrationv1.Rule{}, fmt.Errorf("invalid resource %q, must be [group/]version/resource", resource)
+	}
+}
+
+// Register creates the webhook configuration or updates the existing one
+func Register(ctx context.Context, client kubernetes.Interface, config *admissionregistrationv1.ValidatingWebhookConfiguration) error {
+	configurations := client.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	existing, err := configurations.Get(ctx, config.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := configurations.Create(ctx, config, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create webhook configuration %s: %w", config.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get webhook configuration %s: %w", config.Name, err)
+	}
+	config = config.DeepCopy()
+	config.ResourceVersion = existing.ResourceVersion
+	if _, err := configurations.Update(ctx, config, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update webhook configuration %s: %w", config.Name, err)
+	}
+	return nil
+}
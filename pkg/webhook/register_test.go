@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func Test_Register(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	path := PolicyValidationPath
+	config := PolicyWebhookConfiguration(admissionregistrationv1.WebhookClientConfig{
+		Service:  &admissionregistrationv1.ServiceReference{Name: "verifier", Namespace: "default", Path: &path},
+		CABundle: []byte("first"),
+	})
+	assert.NoError(t, Register(ctx, client, config))
+
+	config = PolicyWebhookConfiguration(admissionregistrationv1.WebhookClientConfig{CABundle: []byte("second")})
+	assert.NoError(t, Register(ctx, client, config))
+
+	got, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, PolicyWebhookName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("second"), got.Webhooks[0].ClientConfig.CABundle)
+	assert.Equal(t, []string{"imageverificationpolicies"}, got.Webhooks[0].Rules[0].Resources)
+}
+
+func Test_ImageWebhookConfiguration(t *testing.T) {
+	rules := []admissionregistrationv1.Rule{{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"pods"}}}
+	path := ImageVerificationPath
+	tests := []struct {
+		name         string
+		clientConfig admissionregistrationv1.WebhookClientConfig
+		want         []string
+	}{{
+		name:         "service",
+		clientConfig: admissionregistrationv1.WebhookClientConfig{Service: &admissionregistrationv1.ServiceReference{Name: "verifier", Namespace: "verifier-system", Path: &path}},
+		want:         []string{"kube-system", "verifier-system"},
+	}, {
+		name:         "service in kube-system",
+		clientConfig: admissionregistrationv1.WebhookClientConfig{Service: &admissionregistrationv1.ServiceReference{Name: "verifier", Namespace: "kube-system", Path: &path}},
+		want:         []string{"kube-system"},
+	}, {
+		name:         "url",
+		clientConfig: admissionregistrationv1.WebhookClientConfig{URL: ptr.To("https://verifier.example.com" + path)},
+		want:         []string{"kube-system"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ImageWebhookConfiguration(tt.clientConfig, rules)
+			assert.Equal(t, tt.want, config.Webhooks[0].NamespaceSelector.MatchExpressions[0].Values)
+		})
+	}
+}
+
+func Test_ParseRule(t *testing.T) {
+	rule, err := ParseRule("v1/pods")
+	assert.NoError(t, err)
+	assert.Equal(t, admissionregistrationv1.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"pods"}}, rule)
+	rule, err = ParseRule("apps/v1/deployments")
+	assert.NoError(t, err)
+	assert.Equal(t, admissionregistrationv1.Rule{APIGroups: []string{"apps"}, APIVersions: []string{"v1"}, Resources: []string{"deployments"}}, rule)
+	for _, resource := range []string{"pods", "apps//deployments", "a/b/c/d"} {
+		_, err = ParseRule(resource)
+		assert.Error(t, err)
+	}
+}
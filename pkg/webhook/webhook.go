@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// maxReviewSize is the maximum size of an admission review body
+const maxReviewSize = 10 << 20
+
+// PolicyValidationPath is the path of the policy validation webhook
+const PolicyValidationPath = "/validate-policy"
+
+// AdmissionHandler returns the response to an admission request
+type AdmissionHandler func(context.Context, *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// Server serves the admission webhooks, the TLS termination is done by the listener given to Run
+type Server struct {
+	handlers map[string]AdmissionHandler
+	synced   []func() bool
+	ready    atomic.Bool
+}
+
+// Option configures the webhooks served by the server
+type Option func(*Server)
+
+// WithImageVerifier serves the image verification webhook on ImageVerificationPath, the server is
+// not ready until the policies of the verifier are loaded
+func WithImageVerifier(verifier *ImageVerifier) Option {
+	return func(s *Server) {
+		s.handlers[ImageVerificationPath] = verifier.Verify
+		s.synced = append(s.synced, verifier.HasSynced)
+	}
+}
+
+// New creates a server validating the policies on PolicyValidationPath
+func New(opts ...Option) *Server {
+	s := &Server{
+		handlers: map[string]AdmissionHandler{
+			PolicyValidationPath: ValidatePolicy,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.ready.Store(true)
+	return s
+}
+
+// Handler returns the HTTP handler of the server. It serves the admission reviews on the webhook
+// paths, liveness on /healthz and readiness on /readyz.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for path, handler := range s.handlers {
+		mux.Handle("POST "+path, serveAdmission(handler))
+	}
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !s.ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		for _, synced := range s.synced {
+			if !synced() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// Run serves on the listener until ctx is done, then stops accepting requests and waits up to
+// shutdownTimeout for the pending requests to complete. The API server only calls webhooks over
+// HTTPS, the listener is expected to be wrapped with tls.NewListener.
+func (s *Server) Run(ctx context.Context, listener net.Listener, shutdownTimeout time.Duration) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.ready.Store(false)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown server: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveAdmission decodes the admission review of the request and replies with the response of the
+// handler, the response UID is always the UID of the request
+func serveAdmission(handler AdmissionHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var review admissionv1.AdmissionReview
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReviewSize)).Decode(&review); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "admission review request is required", http.StatusBadRequest)
+			return
+		}
+		response := handler(r.Context(), review.Request)
+		response.UID = review.Request.UID
+		review.Request = nil
+		review.Response = response
+		review.APIVersion = admissionv1.SchemeGroupVersion.String()
+		review.Kind = "AdmissionReview"
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
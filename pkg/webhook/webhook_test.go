@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const validPolicy = `{"apiVersion":"nirmata.io/v1alpha1","kind":"ImageVerificationPolicy","metadata":{"name":"valid"},"spec":{"rules":[{"name":"any","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/"}],"verify":[{"imageReferences":["ghcr.io/*"]}]}]}}`
+
+const invalidPolicy = `{"apiVersion":"nirmata.io/v1alpha1","kind":"ImageVerificationPolicy","metadata":{"name":"invalid"},"spec":{"rules":[{"name":"any","match":{"any":[{"family":"sample"}]},"imageExtractors":[{"path":"/containerDefinitions/*/image/","jmesPath":"split(@, ':'"}],"verify":[{"imageReferences":["ghcr.io/*"],"cosign":[{"key":{"publicKey":"not a key"}}]}]}]}}`
+
+func review(operation admissionv1.Operation, kind string, object string) admissionv1.AdmissionReview {
+	return admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("uid"),
+			Kind:      metav1.GroupVersionKind{Group: "nirmata.io", Version: "v1alpha1", Kind: kind},
+			Name:      "policy",
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: []byte(object)},
+		},
+	}
+}
+
+func Test_ValidatePolicy(t *testing.T) {
+	srv := httptest.NewServer(New().Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		review      admissionv1.AdmissionReview
+		wantAllowed bool
+		wantCode    int32
+		wantMessage []string
+	}{{
+		name:        "valid policy",
+		review:      review(admissionv1.Create, "ImageVerificationPolicy", validPolicy),
+		wantAllowed: true,
+	}, {
+		name:        "invalid policy",
+		review:      review(admissionv1.Update, "ImageVerificationPolicy", invalidPolicy),
+		wantCode:    http.StatusUnprocessableEntity,
+		wantMessage: []string{"spec.rules[0].imageExtractors[0].jmesPath: Invalid value", "spec.rules[0].verify[0].cosign[0].key.publicKey: Invalid value: invalid PEM data"},
+	}, {
+		name:        "delete",
+		review:      review(admissionv1.Delete, "ImageVerificationPolicy", invalidPolicy),
+		wantAllowed: true,
+	}, {
+		name:        "unexpected kind",
+		review:      review(admissionv1.Create, "Pod", validPolicy),
+		wantCode:    http.StatusBadRequest,
+		wantMessage: []string{"unexpected kind"},
+	}, {
+		name:        "malformed object",
+		review:      review(admissionv1.Create, "ImageVerificationPolicy", `{"spec":"invalid"}`),
+		wantCode:    http.StatusBadRequest,
+		wantMessage: []string{"failed to decode policy"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.review)
+			assert.NoError(t, err)
+			resp, err := http.Post(srv.URL+PolicyValidationPath, "application/json", bytes.NewReader(body))
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			var got admissionv1.AdmissionReview
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			assert.Equal(t, "AdmissionReview", got.Kind)
+			assert.Nil(t, got.Request)
+			assert.Equal(t, types.UID("uid"), got.Response.UID)
+			assert.Equal(t, tt.wantAllowed, got.Response.Allowed)
+			if tt.wantAllowed {
+				assert.Nil(t, got.Response.Result)
+				return
+			}
+			assert.Equal(t, tt.wantCode, got.Response.Result.Code)
+			for _, message := range tt.wantMessage {
+				assert.Contains(t, got.Response.Result.Message, message)
+			}
+		})
+	}
+}
+
+func Test_ServeAdmission_BadRequest(t *testing.T) {
+	handler := New().Handler()
+	for _, body := range []string{`{`, `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, PolicyValidationPath, bytes.NewReader([]byte(body))))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func Test_Run(t *testing.T) {
+	certs, err := GenerateCertificates([]string{"127.0.0.1"}, time.Hour)
+	assert.NoError(t, err)
+	cert, err := tls.X509KeyPair(certs.Cert, certs.Key)
+	assert.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx, tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{cert}}), time.Second)
+	}()
+
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(certs.CA))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		resp, err := client.Get("https://" + listener.Addr().String() + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	assert.False(t, s.ready.Load())
+}